@@ -0,0 +1,106 @@
+package sharding
+
+import (
+	"errors"
+	"sync"
+)
+
+// AnyCluster is a Cluster keyed by an arbitrary type, e.g. a struct.
+// Keys are encoded to bytes and routed by the underlying Cluster.
+type AnyCluster[KeyType any, ConnType any] interface {
+
+	// All returns all shards.
+	All() []Shard[ConnType]
+
+	// One returns Shard by key.
+	One(key KeyType) Shard[ConnType]
+
+	// Each runs fn on each shard within cluster.
+	Each(fn func(s Shard[ConnType]) error) error
+
+	// Map takes a list of keys and returns a map[] where the key is the corresponding
+	// shard and the value is a slice of keys that belong to shard.
+	Map(keys []KeyType) map[Shard[ConnType]][]KeyType
+
+	// ByKeys executes fn on each result of Map func.
+	ByKeys(keys []KeyType, fn func([]KeyType, Shard[ConnType]) error) error
+
+	// Cluster returns the underlying Cluster keyed by encoded keys.
+	Cluster() Cluster[[]byte, ConnType]
+}
+
+// ConnectAny connects to database like Connect, but drops the ID constraint
+// on keys. Each key is routed by encode(key), so the encoding must be
+// deterministic and stable across restarts. cfg.Strategy, if set, receives
+// encoded keys.
+func ConnectAny[KeyType any, ConnType any](
+	cfg Config[[]byte, ConnType],
+	encode func(KeyType) []byte,
+) (AnyCluster[KeyType, ConnType], error) {
+	if encode == nil {
+		return nil, errors.New("encode func cannot be nil")
+	}
+	c, err := Connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &anyCluster[KeyType, ConnType]{c, encode}, nil
+}
+
+type anyCluster[KeyType any, ConnType any] struct {
+	c      Cluster[[]byte, ConnType]
+	encode func(KeyType) []byte
+}
+
+// All returns all shards.
+func (a *anyCluster[KeyType, ConnType]) All() []Shard[ConnType] {
+	return a.c.All()
+}
+
+// One returns Shard by key.
+func (a *anyCluster[KeyType, ConnType]) One(key KeyType) Shard[ConnType] {
+	return a.c.One(a.encode(key))
+}
+
+// Each runs fn on each shard within cluster.
+func (a *anyCluster[KeyType, ConnType]) Each(fn func(s Shard[ConnType]) error) error {
+	return a.c.Each(fn)
+}
+
+// Map takes a list of keys and returns a map[] where the key is the corresponding
+// shard and the value is a slice of keys that belong to shard.
+func (a *anyCluster[KeyType, ConnType]) Map(keys []KeyType) map[Shard[ConnType]][]KeyType {
+	res := make(map[Shard[ConnType]][]KeyType, len(keys))
+	for _, key := range keys {
+		s := a.One(key)
+		if _, ok := res[s]; !ok {
+			res[s] = make([]KeyType, 0, len(keys))
+		}
+		res[s] = append(res[s], key)
+	}
+	return res
+}
+
+// ByKeys executes fn on each result of Map func.
+func (a *anyCluster[KeyType, ConnType]) ByKeys(keys []KeyType, fn func([]KeyType, Shard[ConnType]) error) error {
+	m := a.Map(keys)
+	wg := sync.WaitGroup{}
+	errCh := make(chan error, len(m))
+	for s, k := range m {
+		wg.Add(1)
+		go func(keys []KeyType, sh Shard[ConnType]) {
+			defer wg.Done()
+			if err := safeCall(sh.ID(), func() error { return fn(keys, sh) }); err != nil {
+				errCh <- &ShardError{sh.ID(), err}
+			}
+		}(k, s)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+// Cluster returns the underlying Cluster keyed by encoded keys.
+func (a *anyCluster[KeyType, ConnType]) Cluster() Cluster[[]byte, ConnType] {
+	return a.c
+}
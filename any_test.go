@@ -0,0 +1,123 @@
+package sharding
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type tenantUser struct {
+	TenantID int64
+	UserID   int64
+}
+
+func encodeTenantUser(k tenantUser) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(k.TenantID))
+	binary.BigEndian.PutUint64(b[8:], uint64(k.UserID))
+	return b
+}
+
+func connectTenantUsers(t *testing.T) AnyCluster[tenantUser, int64] {
+	t.Helper()
+	c, err := ConnectAny[tenantUser, int64](Config[[]byte, int64]{
+		Connect: func(_ context.Context, addr string) (int64, error) {
+			return int64(len(addr)), nil
+		},
+		Shards: []ShardConfig{
+			{ID: 1, Addr: "1"},
+			{ID: 2, Addr: "22"},
+			{ID: 3, Addr: "333"},
+		},
+	}, encodeTenantUser)
+	if err != nil {
+		t.Fatalf("ConnectAny() error = %v", err)
+	}
+	return c
+}
+
+func TestConnectAny(t *testing.T) {
+	c := connectTenantUsers(t)
+	h := NewDefaultHash[[]byte]()
+	keys := make([]tenantUser, 0, 30)
+	for tenant := int64(1); tenant <= 3; tenant++ {
+		for user := int64(1); user <= 10; user++ {
+			keys = append(keys, tenantUser{tenant, user})
+		}
+	}
+	seen := map[int64]bool{}
+	for _, k := range keys {
+		want := c.All()[h.Sum(encodeTenantUser(k))%3]
+		got := c.One(k)
+		if got != want {
+			t.Errorf("One(%v) = %v, want %v", k, got.ID(), want.ID())
+		}
+		if c.Cluster().One(encodeTenantUser(k)) != got {
+			t.Errorf("One(%v) differs from underlying cluster", k)
+		}
+		seen[got.ID()] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("keys hit %d shards, want 3", len(seen))
+	}
+	m := c.Map(keys)
+	total := 0
+	for s, ks := range m {
+		for _, k := range ks {
+			if c.One(k) != s {
+				t.Errorf("Map() put %v to shard %d", k, s.ID())
+			}
+		}
+		total += len(ks)
+	}
+	if total != len(keys) {
+		t.Errorf("Map() total keys = %d, want %d", total, len(keys))
+	}
+
+	if _, err := ConnectAny[tenantUser, int64](Config[[]byte, int64]{}, nil); err == nil {
+		t.Error("ConnectAny() error = nil, want error for nil encode")
+	}
+	if _, err := ConnectAny[tenantUser, int64](Config[[]byte, int64]{}, encodeTenantUser); err == nil {
+		t.Error("ConnectAny() error = nil, want error for empty config")
+	}
+}
+
+func Test_anyCluster_fanOut(t *testing.T) {
+	c := connectTenantUsers(t)
+	var ids []int64
+	if err := c.Each(func(s Shard[int64]) error { return nil }); err != nil {
+		t.Errorf("Each() error = %v", err)
+	}
+	for _, s := range c.All() {
+		ids = append(ids, s.ID())
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	if !reflect.DeepEqual(ids, []int64{1, 2, 3}) {
+		t.Errorf("All() ids = %v", ids)
+	}
+	keys := []tenantUser{{1, 1}, {1, 2}, {2, 1}, {2, 2}, {3, 3}}
+	err := c.ByKeys(keys, func(ks []tenantUser, s Shard[int64]) error {
+		for _, k := range ks {
+			if c.One(k) != s {
+				return errors.New("misrouted key")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ByKeys() error = %v", err)
+	}
+	if err = c.ByKeys(keys, func([]tenantUser, Shard[int64]) error {
+		return errors.New("error")
+	}); err == nil {
+		t.Error("ByKeys() error = nil, want error")
+	}
+	if err = c.ByKeys(keys, func([]tenantUser, Shard[int64]) error {
+		panic("boom")
+	}); err == nil {
+		t.Error("ByKeys() error = nil, want panic error")
+	}
+}
@@ -0,0 +1,77 @@
+package sharding
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrShardOpen is returned for shards skipped by Config.Breaker.
+var ErrShardOpen = errors.New("shard circuit breaker is open")
+
+// Breaker is a per-shard circuit breaker consulted by EachCtx and ByKeysCtx
+// (and so Each and ByKeys). fn is not called for a shard when Allow returns
+// false, ErrShardOpen is returned for it instead. Otherwise the result of fn
+// is passed to Record.
+type Breaker interface {
+	Allow(id int64) bool
+	Record(id int64, err error)
+}
+
+// NewBreaker returns a Breaker which opens for a shard after the given
+// number of consecutive failures and lets calls through again after
+// cooldown. A success closes it, a failure after cooldown opens it again.
+func NewBreaker(failures int, cooldown time.Duration) Breaker {
+	if failures < 1 {
+		failures = 1
+	}
+	return &breaker{
+		failures: failures,
+		cooldown: cooldown,
+		state:    make(map[int64]*breakerState),
+		now:      time.Now,
+	}
+}
+
+type breaker struct {
+	failures int
+	cooldown time.Duration
+	mu       sync.Mutex
+	state    map[int64]*breakerState
+	now      func() time.Time
+}
+
+type breakerState struct {
+	failures int
+	openedAt time.Time
+}
+
+// Allow reports whether a call to shard id may be made.
+func (b *breaker) Allow(id int64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.state[id]
+	if !ok || st.failures < b.failures {
+		return true
+	}
+	return b.now().Sub(st.openedAt) >= b.cooldown
+}
+
+// Record records the result of a call to shard id.
+func (b *breaker) Record(id int64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil {
+		delete(b.state, id)
+		return
+	}
+	st, ok := b.state[id]
+	if !ok {
+		st = &breakerState{}
+		b.state[id] = st
+	}
+	st.failures++
+	if st.failures >= b.failures {
+		st.openedAt = b.now()
+	}
+}
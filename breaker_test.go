@@ -0,0 +1,94 @@
+package sharding
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBreaker(2, time.Minute).(*breaker)
+	b.now = func() time.Time { return now }
+	errFn := errors.New("error")
+
+	steps := []struct {
+		name  string
+		do    func()
+		allow map[int64]bool
+	}{
+		{"initially closed", func() {}, map[int64]bool{1: true, 2: true}},
+		{"one failure", func() { b.Record(1, errFn) }, map[int64]bool{1: true, 2: true}},
+		{"success resets", func() { b.Record(1, nil); b.Record(1, errFn) }, map[int64]bool{1: true, 2: true}},
+		{"opened", func() { b.Record(1, errFn) }, map[int64]bool{1: false, 2: true}},
+		{"cooling down", func() { now = now.Add(time.Minute - time.Second) }, map[int64]bool{1: false, 2: true}},
+		{"half-open", func() { now = now.Add(time.Second) }, map[int64]bool{1: true, 2: true}},
+		{"reopened", func() { b.Record(1, errFn) }, map[int64]bool{1: false, 2: true}},
+		{"closed", func() { now = now.Add(time.Minute); b.Record(1, nil) }, map[int64]bool{1: true, 2: true}},
+	}
+	for _, st := range steps {
+		st.do()
+		for id, want := range st.allow {
+			if got := b.Allow(id); got != want {
+				t.Errorf("%s: Allow(%d) = %v, want %v", st.name, id, got, want)
+			}
+		}
+	}
+}
+
+func Test_cluster_Breaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBreaker(2, time.Minute).(*breaker)
+	b.now = func() time.Time { return now }
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+		},
+		breaker: b,
+	}
+	var (
+		mu    sync.Mutex
+		calls map[int64]int
+		down  = true
+	)
+	errDown := errors.New("down")
+	each := func() error {
+		calls = map[int64]int{}
+		return c.Each(func(s Shard[struct{}]) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[s.ID()]++
+			if s.ID() == 2 && down {
+				return errDown
+			}
+			return nil
+		})
+	}
+	for i := 0; i < 2; i++ {
+		if err := each(); !errors.Is(err, errDown) {
+			t.Fatalf("Each() error = %v, want %v", err, errDown)
+		}
+	}
+	err := each()
+	var se *ShardError
+	if !errors.Is(err, ErrShardOpen) || !errors.As(err, &se) || se.ID != 2 {
+		t.Errorf("Each() error = %v, want shard 2 %v", err, ErrShardOpen)
+	}
+	if calls[1] != 1 || calls[2] != 0 {
+		t.Errorf("Each() calls = %v, want shard 1 only", calls)
+	}
+
+	down = false
+	now = now.Add(time.Minute)
+	if err = each(); err != nil {
+		t.Errorf("Each() error = %v after cooldown", err)
+	}
+	if calls[2] != 1 {
+		t.Errorf("Each() calls = %v after cooldown, want shard 2 called", calls)
+	}
+	if !b.Allow(2) {
+		t.Error("Allow(2) = false after success, want closed")
+	}
+}
@@ -0,0 +1,74 @@
+package sharding
+
+import "sync"
+
+// CacheAside reads key from the cache shard it's routed to by Cluster.One
+// with get. On a miss the value is read with load and stored with set. Get
+// and set errors are returned as *ShardError, a failed set still returns
+// the loaded value.
+func CacheAside[KeyType ID, ConnType any, V any](
+	c Cluster[KeyType, ConnType],
+	key KeyType,
+	get func(Shard[ConnType], KeyType) (V, bool, error),
+	set func(Shard[ConnType], KeyType, V) error,
+	load func(KeyType) (V, error),
+) (V, error) {
+	s := c.One(key)
+	v, ok, err := get(s, key)
+	if err != nil {
+		return v, &ShardError{s.ID(), err}
+	}
+	if ok {
+		return v, nil
+	}
+	if v, err = load(key); err != nil {
+		return v, err
+	}
+	if err = set(s, key, v); err != nil {
+		return v, &ShardError{s.ID(), err}
+	}
+	return v, nil
+}
+
+// MultiGet groups keys by shard with Cluster.Map, runs get on each group
+// concurrently and merges the hits. Keys get didn't return are reported
+// as missed in input order, including keys of failed shards, so they can
+// be loaded from the source of truth. Errors are joined. []byte keys are
+// not supported as they can't be map keys.
+func MultiGet[KeyType interface {
+	ID
+	comparable
+}, ConnType any, V any](
+	c Cluster[KeyType, ConnType],
+	keys []KeyType,
+	get func(Shard[ConnType], []KeyType) (map[KeyType]V, error),
+) (map[KeyType]V, []KeyType, error) {
+	var (
+		m    = c.Map(keys)
+		list = make([]Shard[ConnType], 0, len(m))
+		mu   sync.Mutex
+		hits = make(map[KeyType]V, len(keys))
+	)
+	for s := range m {
+		list = append(list, s)
+	}
+	err := eachJoin(list, func(s Shard[ConnType]) error {
+		r, err := get(s, m[s])
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for k, v := range r {
+			hits[k] = v
+		}
+		mu.Unlock()
+		return nil
+	})
+	var missed []KeyType
+	for _, k := range keys {
+		if _, ok := hits[k]; !ok {
+			missed = append(missed, k)
+		}
+	}
+	return hits, missed, err
+}
@@ -0,0 +1,124 @@
+package sharding
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestCacheAside(t *testing.T) {
+	c := &cluster[uint64, map[uint64]string]{
+		list: []Shard[map[uint64]string]{
+			NewShard(1, map[uint64]string{}),
+			NewShard(2, map[uint64]string{}),
+			NewShard(3, map[uint64]string{}),
+		},
+		calc: NewDefaultStrategy[uint64, map[uint64]string](nil),
+	}
+	get := func(s Shard[map[uint64]string], key uint64) (string, bool, error) {
+		v, ok := s.Conn()[key]
+		return v, ok, nil
+	}
+	set := func(s Shard[map[uint64]string], key uint64, v string) error {
+		s.Conn()[key] = v
+		return nil
+	}
+	loads := 0
+	load := func(uint64) (string, error) {
+		loads++
+		return "value", nil
+	}
+
+	for _, wantLoads := range []int{1, 1} {
+		got, err := CacheAside[uint64, map[uint64]string](c, 2, get, set, load)
+		if err != nil {
+			t.Errorf("CacheAside() error = %v", err)
+		}
+		if got != "value" {
+			t.Errorf("CacheAside() = %q, want %q", got, "value")
+		}
+		if loads != wantLoads {
+			t.Errorf("CacheAside() loaded %d times, want %d", loads, wantLoads)
+		}
+	}
+	// key 2 is on shard 3
+	if got := c.list[2].Conn()[2]; got != "value" {
+		t.Errorf("CacheAside() stored %q on shard 3, want %q", got, "value")
+	}
+
+	errGet := errors.New("get error")
+	_, err := CacheAside[uint64, map[uint64]string](c, 1, func(Shard[map[uint64]string], uint64) (string, bool, error) {
+		return "", false, errGet
+	}, set, load)
+	var se *ShardError
+	if !errors.As(err, &se) || se.ID != 1 || !errors.Is(err, errGet) {
+		t.Errorf("CacheAside() error = %v, want shard 1 get error", err)
+	}
+
+	errLoad := errors.New("load error")
+	_, err = CacheAside[uint64, map[uint64]string](c, 1, get, set, func(uint64) (string, error) {
+		return "", errLoad
+	})
+	if !errors.Is(err, errLoad) {
+		t.Errorf("CacheAside() error = %v, want %v", err, errLoad)
+	}
+	if _, ok := c.list[0].Conn()[1]; ok {
+		t.Error("CacheAside() stored a value which failed to load")
+	}
+}
+
+func TestMultiGet(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+	}
+	keys := []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	evens := func(s Shard[struct{}], keys []uint64) (map[uint64]string, error) {
+		res := make(map[uint64]string)
+		for _, k := range keys {
+			if got := c.One(k); got != s {
+				t.Errorf("MultiGet() key %d read from shard %d, want %d", k, s.ID(), got.ID())
+			}
+			if k%2 == 0 {
+				res[k] = strconv.FormatUint(k, 10)
+			}
+		}
+		return res, nil
+	}
+	hits, missed, err := MultiGet[uint64, struct{}](c, keys, evens)
+	if err != nil {
+		t.Errorf("MultiGet() error = %v", err)
+	}
+	wantHits := map[uint64]string{2: "2", 4: "4", 6: "6", 8: "8", 10: "10"}
+	if !reflect.DeepEqual(hits, wantHits) {
+		t.Errorf("MultiGet() hits = %v, want %v", hits, wantHits)
+	}
+	if want := []uint64{1, 3, 5, 7, 9}; !reflect.DeepEqual(missed, want) {
+		t.Errorf("MultiGet() missed = %v, want %v", missed, want)
+	}
+
+	errFn := errors.New("error")
+	hits, missed, err = MultiGet[uint64, struct{}](c, keys, func(s Shard[struct{}], keys []uint64) (map[uint64]string, error) {
+		if s.ID() == 2 {
+			return nil, errFn
+		}
+		return evens(s, keys)
+	})
+	var se *ShardError
+	if !errors.As(err, &se) || se.ID != 2 || !errors.Is(err, errFn) {
+		t.Errorf("MultiGet() error = %v, want shard 2 error", err)
+	}
+	// keys 4 and 9 are on shard 2
+	wantHits = map[uint64]string{2: "2", 6: "6", 8: "8", 10: "10"}
+	if !reflect.DeepEqual(hits, wantHits) {
+		t.Errorf("MultiGet() hits = %v, want %v", hits, wantHits)
+	}
+	if want := []uint64{1, 3, 4, 5, 7, 9}; !reflect.DeepEqual(missed, want) {
+		t.Errorf("MultiGet() missed = %v, want %v", missed, want)
+	}
+}
@@ -0,0 +1,190 @@
+package sharding
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ShardsConfigFromFile reads shard configs from a JSON (.json) or YAML
+// (.yaml, .yml) file. Both contain a list of shards using ShardConfig json
+// keys, e.g.:
+//
+//	# shards.yaml
+//	- id: 1
+//	  dsn: postgres://localhost:5432/db1
+//	  read_dsn:
+//	    - postgres://localhost:5433/db1
+//	- id: 2
+//	  dsn: postgres://localhost:5432/db2
+//
+// YAML support is limited to this layout, which keeps the package free of
+// dependencies.
+func ShardsConfigFromFile(path string) ([]ShardConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var shards []ShardConfig
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		if err = json.Unmarshal(data, &shards); err != nil {
+			return nil, fmt.Errorf("%s is malformed: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		if shards, err = parseShardsYAML(data); err != nil {
+			return nil, fmt.Errorf("%s is malformed: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported shards config file extension %q", ext)
+	}
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("%s contains no shards", path)
+	}
+	if !areShardsUnique(shards) {
+		return nil, ErrDuplicateShards
+	}
+	return shards, nil
+}
+
+// ShardsFromTemplate returns a shard config for each of ids, with the
+// address made by replacing {id} in template with the shard id, e.g.
+// postgres://localhost:5432/db{id}. It fails when template has no {id}
+// placeholder or ids are not unique.
+func ShardsFromTemplate(template string, ids []int64) ([]ShardConfig, error) {
+	if !strings.Contains(template, "{id}") {
+		return nil, fmt.Errorf("shards address template %q has no {id} placeholder", template)
+	}
+	if len(ids) == 0 {
+		return nil, ErrNoShards
+	}
+	shards := make([]ShardConfig, len(ids))
+	for i, id := range ids {
+		shards[i] = ShardConfig{
+			ID:   id,
+			Addr: strings.ReplaceAll(template, "{id}", strconv.FormatInt(id, 10)),
+		}
+	}
+	if !areShardIDsUnique(shards) {
+		return nil, ErrDuplicateShards
+	}
+	return shards, nil
+}
+
+// parseShardsYAML parses a YAML list of shard configs. Only the subset of
+// YAML needed for ShardConfig is supported: a top level sequence of
+// mappings with scalar id and dsn and a block or flow sequence read_dsn.
+func parseShardsYAML(data []byte) ([]ShardConfig, error) {
+	var (
+		shards     []ShardConfig
+		list       *[]string // block sequence being filled, if any.
+		itemIndent = -1
+	)
+	for i, line := range strings.Split(string(data), "\n") {
+		line = stripYAMLComment(strings.TrimRight(line, " \t\r"))
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		isItem := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+		switch {
+		case isItem && (itemIndent < 0 || indent == itemIndent):
+			itemIndent = indent
+			shards = append(shards, ShardConfig{})
+			list = nil
+			if rest := strings.TrimSpace(trimmed[1:]); rest != "" {
+				var err error
+				if list, err = setYAMLField(&shards[len(shards)-1], rest); err != nil {
+					return nil, fmt.Errorf("yaml: line %d: %w", i+1, err)
+				}
+			}
+		case isItem && indent > itemIndent && list != nil:
+			*list = append(*list, unquoteYAML(strings.TrimSpace(trimmed[1:])))
+		case !isItem && indent > itemIndent && len(shards) > 0:
+			var err error
+			if list, err = setYAMLField(&shards[len(shards)-1], trimmed); err != nil {
+				return nil, fmt.Errorf("yaml: line %d: %w", i+1, err)
+			}
+		default:
+			return nil, fmt.Errorf("yaml: line %d: unexpected %q", i+1, trimmed)
+		}
+	}
+	return shards, nil
+}
+
+// setYAMLField sets a "key: value" pair on cfg. It returns the list to
+// fill when the value is a block sequence starting on the next line.
+func setYAMLField(cfg *ShardConfig, kv string) (*[]string, error) {
+	key, value, ok := strings.Cut(kv, ":")
+	if !ok {
+		return nil, fmt.Errorf("expected key: value, got %q", kv)
+	}
+	value = strings.TrimSpace(value)
+	switch strings.TrimSpace(key) {
+	case "id":
+		id, err := strconv.ParseInt(unquoteYAML(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid id: %w", err)
+		}
+		cfg.ID = id
+	case "dsn":
+		cfg.Addr = unquoteYAML(value)
+	case "weight":
+		weight, err := strconv.Atoi(unquoteYAML(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight: %w", err)
+		}
+		cfg.Weight = weight
+	case "read_dsn":
+		if value == "" {
+			return &cfg.ReadAddrs, nil
+		}
+		if !strings.HasPrefix(value, "[") || !strings.HasSuffix(value, "]") {
+			return nil, fmt.Errorf("invalid read_dsn %q, expected a list", value)
+		}
+		for _, addr := range strings.Split(value[1:len(value)-1], ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				cfg.ReadAddrs = append(cfg.ReadAddrs, unquoteYAML(addr))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown key %q", key)
+	}
+	return nil, nil
+}
+
+// stripYAMLComment removes a trailing # comment which is not quoted.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return line
+}
+
+func unquoteYAML(s string) string {
+	if len(s) >= 2 {
+		switch {
+		case s[0] == '"' && s[len(s)-1] == '"':
+			if u, err := strconv.Unquote(s); err == nil {
+				return u
+			}
+		case s[0] == '\'' && s[len(s)-1] == '\'':
+			return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+		}
+	}
+	return s
+}
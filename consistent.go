@@ -0,0 +1,143 @@
+package sharding
+
+import (
+	"hash/crc64"
+	"sort"
+	"strconv"
+	"sync/atomic"
+)
+
+// ConsistentOptions tunes the ring of NewConsistentStrategy.
+type ConsistentOptions struct {
+	Replicas  int                                   // optional. virtual nodes per shard, defaults to 150.
+	PointHash func(shardID int64, vnode int) uint64 // optional. ring point of a virtual node, defaults to mixed crc64 of "id-vnode".
+}
+
+// NewConsistentStrategy returns Strategy which places shards on a hash ring
+// of opts.Replicas virtual nodes each and picks the shard owning the first
+// point at or after hash of key. Unlike NewDefaultStrategy, adding or
+// removing a shard moves only about 1/N of the keys. More virtual nodes
+// spread keys more evenly at the cost of memory. When hash is nil,
+// NewDefaultHash is used.
+func NewConsistentStrategy[KeyType ID, ConnType any](
+	hash Hash[KeyType],
+	opts ConsistentOptions,
+) Strategy[KeyType, ConnType] {
+	if hash == nil {
+		hash = NewDefaultHash[KeyType]()
+	}
+	if opts.Replicas < 1 {
+		opts.Replicas = 150
+	}
+	if opts.PointHash == nil {
+		t := crc64.MakeTable(crc64.ISO)
+		opts.PointHash = func(shardID int64, vnode int) uint64 {
+			var buf [41]byte
+			b := strconv.AppendInt(buf[:0], shardID, 10)
+			b = append(b, '-')
+			b = strconv.AppendInt(b, int64(vnode), 10)
+			return mix64(crc64.Checksum(b, t))
+		}
+	}
+	return &consistentStrategy[KeyType, ConnType]{hash: hash, opts: opts}
+}
+
+type consistentStrategy[KeyType ID, ConnType any] struct {
+	hash Hash[KeyType]
+	opts ConsistentOptions
+	ring atomic.Pointer[ring[ConnType]]
+}
+
+// ring is a sorted list of virtual node points, cached by the identity of
+// the shard list it was made from.
+type ring[ConnType any] struct {
+	first  *Shard[ConnType]
+	n      int
+	points []uint64
+	shards []Shard[ConnType]
+}
+
+// Find picks the shard owning the first ring point at or after hash of key.
+func (c *consistentStrategy[KeyType, ConnType]) Find(
+	key KeyType,
+	shards []Shard[ConnType],
+) Shard[ConnType] {
+	return c.FindByHash(c.hash.Sum(key), shards)
+}
+
+// HashOf returns the hash of key.
+func (c *consistentStrategy[KeyType, ConnType]) HashOf(key KeyType) uint64 {
+	return c.hash.Sum(key)
+}
+
+// FindByHash picks the shard owning the first ring point at or after sum.
+// The sum is mixed first: crc64 of short keys leaves most of the bits
+// unused, which would place all keys between a few points.
+func (c *consistentStrategy[KeyType, ConnType]) FindByHash(
+	sum uint64,
+	shards []Shard[ConnType],
+) Shard[ConnType] {
+	if len(shards) == 0 {
+		return nil
+	}
+	r := c.ringOf(shards)
+	sum = mix64(sum)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= sum })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.shards[i]
+}
+
+// ringOf returns the ring of shards. Like the sorted list of
+// defaultStrategy, it's cached by the identity of shards, so a list must
+// not be modified in place once passed to Find.
+func (c *consistentStrategy[KeyType, ConnType]) ringOf(shards []Shard[ConnType]) *ring[ConnType] {
+	if len(shards) == 0 {
+		return &ring[ConnType]{}
+	}
+	if r := c.ring.Load(); r != nil && r.first == &shards[0] && r.n == len(shards) {
+		return r
+	}
+	type point struct {
+		sum   uint64
+		shard Shard[ConnType]
+	}
+	points := make([]point, 0, len(shards)*c.opts.Replicas)
+	for _, s := range shards {
+		for v := 0; v < c.opts.Replicas; v++ {
+			points = append(points, point{c.opts.PointHash(s.ID(), v), s})
+		}
+	}
+	// ties are broken by shard id, so the ring doesn't depend on the order
+	// of shards
+	sort.Slice(points, func(i, j int) bool {
+		if points[i].sum != points[j].sum {
+			return points[i].sum < points[j].sum
+		}
+		return points[i].shard.ID() < points[j].shard.ID()
+	})
+	r := &ring[ConnType]{
+		first:  &shards[0],
+		n:      len(shards),
+		points: make([]uint64, len(points)),
+		shards: make([]Shard[ConnType], len(points)),
+	}
+	for i, p := range points {
+		r.points[i] = p.sum
+		r.shards[i] = p.shard
+	}
+	c.ring.Store(r)
+	return r
+}
+
+// mix64 is the 64-bit finalizer of MurmurHash3, it spreads entropy of x
+// over all bits.
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
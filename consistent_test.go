@@ -0,0 +1,59 @@
+package sharding
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestConsistentStrategy(t *testing.T) {
+	shards := make([]Shard[struct{}], 10)
+	for i := range shards {
+		shards[i] = NewShard(int64(i+1), struct{}{})
+	}
+	stddev := func(replicas int) float64 {
+		s := NewConsistentStrategy[uint64, struct{}](nil, ConsistentOptions{Replicas: replicas})
+		load := map[int64]float64{}
+		const keys = 100000
+		for k := uint64(0); k < keys; k++ {
+			load[s.Find(k, shards).ID()]++
+		}
+		mean := float64(keys) / float64(len(shards))
+		var sum float64
+		for _, sh := range shards {
+			d := load[sh.ID()] - mean
+			sum += d * d
+		}
+		return math.Sqrt(sum / float64(len(shards)))
+	}
+	one, many := stddev(1), stddev(150)
+	t.Logf("stddev replicas=1: %.1f, replicas=150: %.1f", one, many)
+	if many >= one {
+		t.Errorf("stddev with 150 replicas = %.1f, want less than %.1f with 1 replica", many, one)
+	}
+}
+
+func TestConsistentStrategy_removeShard(t *testing.T) {
+	shards := []Shard[struct{}]{
+		NewShard(1, struct{}{}),
+		NewShard(2, struct{}{}),
+		NewShard(3, struct{}{}),
+		NewShard(4, struct{}{}),
+	}
+	s := NewConsistentStrategy[string, struct{}](nil, ConsistentOptions{})
+	before := make(map[string]int64)
+	for i := 0; i < 1000; i++ {
+		k := "key" + strconv.Itoa(i)
+		before[k] = s.Find(k, shards).ID()
+	}
+	rest := []Shard[struct{}]{shards[2], shards[0], shards[3]} // shard 2 removed, unordered
+	for k, id := range before {
+		got := s.Find(k, rest).ID()
+		if id != 2 && got != id {
+			t.Errorf("Find(%q) moved from shard %d to %d", k, id, got)
+		}
+		if got == 2 {
+			t.Errorf("Find(%q) = removed shard 2", k)
+		}
+	}
+}
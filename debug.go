@@ -0,0 +1,18 @@
+//go:build sharding_debug
+
+package sharding
+
+import "fmt"
+
+// debug enables internal invariant checks, build with -tags sharding_debug.
+const debug = true
+
+// checkSorted panics unless shards are sorted by id and ids are unique.
+func checkSorted[ConnType any](shards []Shard[ConnType]) {
+	for i := 1; i < len(shards); i++ {
+		if shards[i-1].ID() >= shards[i].ID() {
+			panic(fmt.Sprintf("sharding: shards are not sorted by unique id at %d: %d, %d",
+				i, shards[i-1].ID(), shards[i].ID()))
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package sharding
+
+// Diff returns [old shard id, new shard id] for each of ids whose placement
+// on shards changes when switching from one strategy to another, e.g. to
+// plan data migration before Cluster.SetStrategy. Keys which stay in place
+// are omitted, the result is empty when shards is. []byte keys are not
+// supported as they can't be map keys.
+func Diff[KeyType interface {
+	ID
+	comparable
+}, ConnType any](
+	ids []KeyType,
+	from, to Strategy[KeyType, ConnType],
+	shards []Shard[ConnType],
+) map[KeyType][2]int64 {
+	res := make(map[KeyType][2]int64)
+	if len(shards) == 0 {
+		return res
+	}
+	for _, id := range ids {
+		o, n := from.Find(id, shards).ID(), to.Find(id, shards).ID()
+		if o != n {
+			res[id] = [2]int64{o, n}
+		}
+	}
+	return res
+}
@@ -0,0 +1,36 @@
+package sharding
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	shards := []Shard[struct{}]{
+		NewShard(1, struct{}{}),
+		NewShard(2, struct{}{}),
+		NewShard(3, struct{}{}),
+	}
+	var (
+		modulo     = NewDefaultStrategy[uint64, struct{}](nil)
+		consistent = NewConsistentStrategy[uint64, struct{}](nil, ConsistentOptions{})
+		ids        = []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	)
+	// keys 3, 8 and 10 stay in place
+	want := map[uint64][2]int64{
+		1: {1, 2},
+		2: {3, 2},
+		4: {2, 3},
+		5: {3, 1},
+		6: {3, 2},
+		7: {1, 3},
+		9: {2, 3},
+	}
+	got := Diff(ids, modulo, consistent, shards)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if got := Diff(ids, modulo, modulo, shards); len(got) != 0 {
+		t.Errorf("Diff() with the same strategy = %v, want empty", got)
+	}
+}
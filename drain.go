@@ -0,0 +1,126 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrShardDraining is returned for a shard which started draining after
+// a fan-out call routed to it, see Cluster.DrainShard.
+var ErrShardDraining = errors.New("shard is draining")
+
+// DrainShard excludes shard id from key routing and fan-out calls and
+// waits until fn calls running on it in EachCtx and ByKeysCtx return.
+func (c *cluster[KeyType, ConnType]) DrainShard(ctx context.Context, id int64) error {
+	c.mu.Lock()
+	found := false
+	for _, s := range c.list {
+		if s.ID() == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		c.mu.Unlock()
+		return fmt.Errorf("shard %d not found", id)
+	}
+	if c.draining == nil {
+		c.draining = make(map[int64]bool)
+	}
+	c.draining[id] = true
+	c.mu.Unlock()
+	return c.ops.wait(ctx, id)
+}
+
+// isDraining reports whether shard id is draining.
+func (c *cluster[KeyType, ConnType]) isDraining(id int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.draining[id]
+}
+
+// activeShards returns the shards of c which are not draining, all of them
+// for clusters not created by Connect.
+func activeShards[KeyType ID, ConnType any](c Cluster[KeyType, ConnType]) []Shard[ConnType] {
+	if a, ok := c.(interface{ active() []Shard[ConnType] }); ok {
+		return a.active()
+	}
+	return c.All()
+}
+
+// InFlight returns the number of fn calls running on shard id.
+func (c *cluster[KeyType, ConnType]) InFlight(id int64) int64 {
+	if c.ops == nil {
+		return 0
+	}
+	return c.ops.count(id).Load()
+}
+
+// shardOps counts fn calls in flight by shard id. It's shared by copies
+// of a cluster.
+type shardOps struct {
+	counts sync.Map // int64 -> *atomic.Int64
+	mu     sync.Mutex
+	idle   map[int64]chan struct{} // closed when the count drops to zero.
+}
+
+func newShardOps() *shardOps {
+	return &shardOps{idle: make(map[int64]chan struct{})}
+}
+
+func (o *shardOps) count(id int64) *atomic.Int64 {
+	n, ok := o.counts.Load(id)
+	if !ok {
+		n, _ = o.counts.LoadOrStore(id, new(atomic.Int64))
+	}
+	return n.(*atomic.Int64)
+}
+
+// start counts an operation on shard id, the returned func ends it.
+func (o *shardOps) start(id int64) func() {
+	if o == nil {
+		return func() {}
+	}
+	n := o.count(id)
+	n.Add(1)
+	return func() {
+		if n.Add(-1) > 0 {
+			return
+		}
+		o.mu.Lock()
+		if ch, ok := o.idle[id]; ok {
+			close(ch)
+			delete(o.idle, id)
+		}
+		o.mu.Unlock()
+	}
+}
+
+// wait blocks until no operation is in flight on shard id or ctx is done.
+func (o *shardOps) wait(ctx context.Context, id int64) error {
+	if o == nil {
+		return nil
+	}
+	n := o.count(id)
+	for {
+		o.mu.Lock()
+		if n.Load() == 0 {
+			o.mu.Unlock()
+			return nil
+		}
+		ch, ok := o.idle[id]
+		if !ok {
+			ch = make(chan struct{})
+			o.idle[id] = ch
+		}
+		o.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ch:
+		}
+	}
+}
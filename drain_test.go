@@ -0,0 +1,216 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_cluster_DrainShard(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+		ops:  newShardOps(),
+	}
+	placement := c.Placement([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	var (
+		started = make(chan struct{})
+		release = make(chan struct{})
+		done    = make(chan error)
+	)
+	go func() {
+		done <- c.ByKeys(placement[2], func(_ []uint64, _ Shard[struct{}]) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := c.DrainShard(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DrainShard() error = %v, want %v with an op in flight", err, context.DeadlineExceeded)
+	}
+	for _, key := range placement[2] {
+		if got := c.One(key).ID(); got != 3 {
+			t.Errorf("One(%d) = shard %d with shard 2 draining, want 3", key, got)
+		}
+	}
+	if got := c.Placement(placement[2]); !reflect.DeepEqual(got, map[int64][]uint64{3: placement[2]}) {
+		t.Errorf("Placement() = %v with shard 2 draining, want all on shard 3", got)
+	}
+	for name, fn := range map[string]func(func(Shard[struct{}]) error) error{
+		"Each": c.Each,
+		"ByKeys": func(fn func(Shard[struct{}]) error) error {
+			return c.ByKeys(placement[2], func(_ []uint64, s Shard[struct{}]) error { return fn(s) })
+		},
+		"GroupBy": func(fn func(Shard[struct{}]) error) error {
+			for s := range GroupBy[uint64, struct{}](c, placement[2], func(id uint64) uint64 { return id }) {
+				_ = fn(s)
+			}
+			return nil
+		},
+		"Scatter": func(fn func(Shard[struct{}]) error) error {
+			_, err := Scatter[uint64, struct{}](c, func(s Shard[struct{}]) (int, error) { return 0, fn(s) })
+			return err
+		},
+	} {
+		var mu sync.Mutex
+		_ = fn(func(s Shard[struct{}]) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if s.ID() == 2 {
+				t.Errorf("%s() called fn on draining shard 2", name)
+			}
+			return nil
+		})
+	}
+
+	drained := make(chan error)
+	go func() {
+		drained <- c.DrainShard(context.Background(), 2)
+	}()
+	select {
+	case err := <-drained:
+		t.Fatalf("DrainShard() = %v before the op in flight returned", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("ByKeys() error = %v", err)
+	}
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("DrainShard() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("DrainShard() didn't return after the op in flight returned")
+	}
+
+	if err := c.RemoveShard(2); err != nil {
+		t.Fatalf("RemoveShard() error = %v", err)
+	}
+	if err := c.DrainShard(context.Background(), 2); err == nil {
+		t.Error("DrainShard() of a removed shard error = nil, want not found")
+	}
+}
+
+func Test_cluster_InFlight(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+		ops:  newShardOps(),
+	}
+	var (
+		started = make(chan struct{}, 4)
+		release = make(chan struct{})
+		done    = make(chan error, 2)
+	)
+	go func() {
+		done <- c.Each(func(Shard[struct{}]) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+	}()
+	go func() {
+		// keys 1 and 7 are both on shard 1
+		done <- c.ByKeys([]uint64{1, 7}, func([]uint64, Shard[struct{}]) error {
+			started <- struct{}{}
+			<-release
+			return nil
+		})
+	}()
+	for i := 0; i < 4; i++ {
+		<-started
+	}
+	want := map[int64]int64{1: 2, 2: 1, 3: 1}
+	for id, n := range want {
+		if got := c.InFlight(id); got != n {
+			t.Errorf("InFlight(%d) = %d, want %d", id, got, n)
+		}
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		if err := <-done; err != nil {
+			t.Errorf("Each() or ByKeys() error = %v", err)
+		}
+	}
+	for id := range want {
+		if got := c.InFlight(id); got != 0 {
+			t.Errorf("InFlight(%d) = %d after ops returned, want 0", id, got)
+		}
+	}
+}
+
+// gateBreaker blocks Allow for shard id until gate is closed.
+type gateBreaker struct {
+	id      int64
+	entered chan struct{}
+	gate    chan struct{}
+}
+
+func (b *gateBreaker) Allow(id int64) bool {
+	if id == b.id {
+		close(b.entered)
+		<-b.gate
+	}
+	return true
+}
+
+func (*gateBreaker) Record(int64, error) {}
+
+func Test_cluster_DrainShard_routedBeforeDrain(t *testing.T) {
+	b := &gateBreaker{id: 2, entered: make(chan struct{}), gate: make(chan struct{})}
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+		calc:    NewDefaultStrategy[uint64, struct{}](nil),
+		ops:     newShardOps(),
+		breaker: b,
+	}
+	var (
+		mu     sync.Mutex
+		called []int64
+		done   = make(chan error)
+	)
+	go func() {
+		done <- c.Each(func(s Shard[struct{}]) error {
+			mu.Lock()
+			defer mu.Unlock()
+			called = append(called, s.ID())
+			return nil
+		})
+	}()
+	// Each routed to shard 2 but hasn't counted it in flight yet
+	<-b.entered
+	if err := c.DrainShard(context.Background(), 2); err != nil {
+		t.Fatalf("DrainShard() error = %v", err)
+	}
+	close(b.gate)
+	if err := <-done; !errors.Is(err, ErrShardDraining) {
+		t.Errorf("Each() error = %v, want %v", err, ErrShardDraining)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if slices.Contains(called, 2) {
+		t.Errorf("Each() called fn on shard 2 after DrainShard returned, called = %v", called)
+	}
+}
@@ -0,0 +1,57 @@
+package sharding
+
+import "math"
+
+// StrategyReport describes how evenly a Strategy distributes keys, see
+// EvaluateStrategy.
+type StrategyReport struct {
+	Counts map[int64]int // number of keys per shard id, including empty shards.
+	Min    int           // number of keys on the least loaded shard.
+	Max    int           // number of keys on the most loaded shard.
+	StdDev float64       // population standard deviation of Counts.
+	Gini   float64       // Gini coefficient of Counts: 0 is a perfectly even load, close to 1 is all keys on one shard.
+}
+
+// EvaluateStrategy places keys on shards with s and reports the resulting
+// load distribution, e.g. to compare strategies against a sample of real
+// keys before switching.
+func EvaluateStrategy[KeyType ID, ConnType any](
+	s Strategy[KeyType, ConnType],
+	shards []Shard[ConnType],
+	keys []KeyType,
+) StrategyReport {
+	r := StrategyReport{Counts: make(map[int64]int, len(shards))}
+	if len(shards) == 0 {
+		return r
+	}
+	for _, sh := range shards {
+		r.Counts[sh.ID()] = 0
+	}
+	for _, k := range keys {
+		r.Counts[s.Find(k, shards).ID()]++
+	}
+	var (
+		n    = float64(len(r.Counts))
+		mean = float64(len(keys)) / n
+		sq   float64
+		diff float64
+	)
+	r.Min = len(keys)
+	for _, c := range r.Counts {
+		if c < r.Min {
+			r.Min = c
+		}
+		if c > r.Max {
+			r.Max = c
+		}
+		sq += (float64(c) - mean) * (float64(c) - mean)
+		for _, o := range r.Counts {
+			diff += math.Abs(float64(c - o))
+		}
+	}
+	r.StdDev = math.Sqrt(sq / n)
+	if mean > 0 {
+		r.Gini = diff / (2 * n * n * mean)
+	}
+	return r
+}
@@ -0,0 +1,57 @@
+package sharding
+
+import (
+	"reflect"
+	"testing"
+)
+
+type roundRobinStrategy[KeyType ID, ConnType any] struct{}
+
+func (roundRobinStrategy[KeyType, ConnType]) Find(key KeyType, shards []Shard[ConnType]) Shard[ConnType] {
+	return shards[any(key).(uint64)%uint64(len(shards))]
+}
+
+func (roundRobinStrategy[KeyType, ConnType]) FindByHash(sum uint64, shards []Shard[ConnType]) Shard[ConnType] {
+	return shards[sum%uint64(len(shards))]
+}
+
+func TestEvaluateStrategy(t *testing.T) {
+	shards := []Shard[struct{}]{
+		NewShard(1, struct{}{}),
+		NewShard(2, struct{}{}),
+		NewShard(3, struct{}{}),
+	}
+	keys := make([]uint64, 300)
+	for i := range keys {
+		keys[i] = uint64(i)
+	}
+	tests := []struct {
+		name     string
+		strategy Strategy[uint64, struct{}]
+		want     StrategyReport
+	}{
+		{
+			"uniform",
+			roundRobinStrategy[uint64, struct{}]{},
+			StrategyReport{Counts: map[int64]int{1: 100, 2: 100, 3: 100}, Min: 100, Max: 100},
+		},
+		{
+			"single shard",
+			firstStrategy[uint64, struct{}]{},
+			StrategyReport{
+				Counts: map[int64]int{1: 300, 2: 0, 3: 0},
+				Min:    0,
+				Max:    300,
+				StdDev: 141.4213562373095,
+				Gini:   2.0 / 3,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EvaluateStrategy(tt.strategy, shards, keys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EvaluateStrategy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
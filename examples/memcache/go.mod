@@ -1,6 +1,6 @@
 module github.com/skamenetskiy/sharding/examples/memcache
 
-go 1.19
+go 1.23
 
 replace github.com/skamenetskiy/sharding => ../../
 
@@ -9,4 +9,4 @@ require (
 	github.com/skamenetskiy/sharding v0.0.0-00010101000000-000000000000
 )
 
-require github.com/rs/xid v1.4.0 // indirect
+require github.com/rs/xid v1.4.0
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"sync"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/rs/xid"
@@ -63,30 +62,19 @@ func main() {
 		}
 	}
 
-	type item struct {
-		id, value string
-	}
-
-	res := make([]item, 0, 10)
-	mu := sync.Mutex{}
-
 	// query items from all shards in parallel
-	if err = cluster.ByKeys(ids, func(ids []string, s sharding.Shard[*memcache.Client]) error {
-		items, err := s.Conn().GetMulti(ids)
-		if err != nil {
-			return err
-		}
-		for _, i := range items {
-			v := item{i.Key, string(i.Value)}
-			mu.Lock()
-			res = append(res, v)
-			mu.Unlock()
-		}
-		return nil
-	}); err != nil {
+	items, missed, err := sharding.MultiGet(cluster, ids, func(s sharding.Shard[*memcache.Client], ids []string) (map[string]*memcache.Item, error) {
+		return s.Conn().GetMulti(ids)
+	})
+	if err != nil {
 		log.Fatalf("failed to select: %s\n", err)
 	}
 
 	// print out the result
-	log.Println(res)
+	for _, id := range ids {
+		if i, ok := items[id]; ok {
+			log.Println(i.Key, string(i.Value))
+		}
+	}
+	log.Println("missed:", missed)
 }
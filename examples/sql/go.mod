@@ -1,6 +1,6 @@
 module github.com/skamenetskiy/sharding/examples/sql
 
-go 1.19
+go 1.23
 
 replace github.com/skamenetskiy/sharding => ../../
 
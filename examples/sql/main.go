@@ -45,6 +45,10 @@ func main() {
 			Context: context.Background(),
 			Connect: connect,
 			Shards:  shards,
+			// sql.Open doesn't dial, make sure every shard is reachable
+			ValidateConn: func(ctx context.Context, db *sql.DB) error {
+				return db.PingContext(ctx)
+			},
 		},
 	)
 	if err != nil {
@@ -60,20 +64,42 @@ func main() {
 		log.Fatalf("failed to create tables: %s\n", err)
 	}
 
-	// insert 10 rows, each to corresponding shard
+	type item struct {
+		id, value string
+	}
+
+	// prepare 10 rows
+	items := make([]item, 0, 10)
 	ids := make([]string, 0, 10)
 	for i := 0; i < 10; i++ {
 		id := xid.New().String()
-		value := fmt.Sprintf("sharding example [index: %d, id: %s]", i, id)
+		items = append(items, item{id, fmt.Sprintf("sharding example [index: %d, id: %s]", i, id)})
 		ids = append(ids, id)
-		if _, err = cluster.One(id).Conn().
-			Exec("INSERT INTO sample (id, value) VALUES ($1, $2)", id, value); err != nil {
-			log.Fatalf("failed to insert: %s\n", err)
-		}
 	}
 
-	type item struct {
-		id, value string
+	// insert rows with a single statement per shard owning the keys
+	if err = sharding.ByGroups(cluster, items, func(i item) string {
+		return i.id
+	}, func(s sharding.Shard[*sql.DB], items []item) error {
+		values := make([]string, len(items))
+		args := make([]any, 0, len(items)*2)
+		for i, it := range items {
+			values[i] = fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2)
+			args = append(args, it.id, it.value)
+		}
+		query := fmt.Sprintf("INSERT INTO sample (id, value) VALUES %s", strings.Join(values, ","))
+		// insert within a transaction on the shard
+		return sharding.Transact(s.Conn(),
+			func(db *sql.DB) (*sql.Tx, error) {
+				return db.Begin()
+			},
+			func(tx *sql.Tx) error {
+				_, err := tx.Exec(query, args...)
+				return err
+			},
+		)
+	}); err != nil {
+		log.Fatalf("failed to insert: %s\n", err)
 	}
 
 	res := make([]item, 0, 10)
@@ -0,0 +1,33 @@
+package sharding
+
+import "fmt"
+
+// NewFixedStrategy returns Strategy which places every key on the shard
+// with the given id, e.g. for local development or single shard deployments
+// that will grow later. Find panics when there's no such shard in a non
+// empty list.
+func NewFixedStrategy[KeyType ID, ConnType any](shardID int64) Strategy[KeyType, ConnType] {
+	return fixedStrategy[KeyType, ConnType]{id: shardID}
+}
+
+type fixedStrategy[KeyType ID, ConnType any] struct {
+	id int64
+}
+
+// Find returns the fixed shard.
+func (f fixedStrategy[KeyType, ConnType]) Find(_ KeyType, shards []Shard[ConnType]) Shard[ConnType] {
+	return f.FindByHash(0, shards)
+}
+
+// FindByHash returns the fixed shard.
+func (f fixedStrategy[KeyType, ConnType]) FindByHash(_ uint64, shards []Shard[ConnType]) Shard[ConnType] {
+	if len(shards) == 0 {
+		return nil
+	}
+	for _, s := range shards {
+		if s.ID() == f.id {
+			return s
+		}
+	}
+	panic(fmt.Sprintf("sharding: fixed strategy shard %d not found", f.id))
+}
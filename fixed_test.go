@@ -0,0 +1,40 @@
+package sharding
+
+import (
+	"testing"
+)
+
+func TestFixedStrategy(t *testing.T) {
+	shards := []Shard[struct{}]{
+		NewShard(1, struct{}{}),
+		NewShard(2, struct{}{}),
+		NewShard(3, struct{}{}),
+	}
+	tests := []struct {
+		name      string
+		id        int64
+		wantPanic bool
+	}{
+		{"first", 1, false},
+		{"last", 3, false},
+		{"missing", 4, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tt.wantPanic {
+					t.Errorf("Find() panic = %v, wantPanic %v", r, tt.wantPanic)
+				}
+			}()
+			s := NewFixedStrategy[uint64, struct{}](tt.id)
+			for key := uint64(0); key < 10; key++ {
+				if got := s.Find(key, shards); got.ID() != tt.id {
+					t.Errorf("Find(%d) = shard %d, want %d", key, got.ID(), tt.id)
+				}
+			}
+			if got := s.FindByHash(42, shards); got.ID() != tt.id {
+				t.Errorf("FindByHash() = shard %d, want %d", got.ID(), tt.id)
+			}
+		})
+	}
+}
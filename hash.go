@@ -0,0 +1,117 @@
+package sharding
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash/crc64"
+)
+
+// NewSHA256Hash returns a Hash which folds the SHA-256 digest of the key
+// into uint64 (first 8 bytes, big-endian). Keys are encoded the same way
+// as in NewDefaultHash. It is slower than the default crc64 hash, but
+// distributes short sequential keys evenly across any number of shards.
+func NewSHA256Hash[KeyType ID]() Hash[KeyType] {
+	return sha256Hash[KeyType]{}
+}
+
+type sha256Hash[KeyType ID] struct{}
+
+// Sum of id.
+func (sha256Hash[KeyType]) Sum(id KeyType) uint64 {
+	sum := sha256.Sum256(encodeKey(id))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// NewDefaultHashBinary returns a crc64 Hash like NewDefaultHash, but int64
+// and uint64 keys are encoded as 8 little-endian bytes instead of decimal
+// text, which avoids an allocation per key and keeps integer keys from
+// colliding with string keys that look the same.
+//
+// Integer keys are placed differently than with NewDefaultHash, so switching
+// an existing cluster between the two requires moving the data.
+func NewDefaultHashBinary[KeyType ID]() Hash[KeyType] {
+	return &defaultHashBinary[KeyType]{crc64.MakeTable(crc64.ISO)}
+}
+
+type defaultHashBinary[KeyType ID] struct {
+	t *crc64.Table
+}
+
+// Sum of id.
+func (h *defaultHashBinary[KeyType]) Sum(id KeyType) uint64 {
+	var buf [8]byte
+	switch i := any(id).(type) {
+	case int64:
+		binary.LittleEndian.PutUint64(buf[:], uint64(i))
+		return crc64.Checksum(buf[:], h.t)
+	case uint64:
+		binary.LittleEndian.PutUint64(buf[:], i)
+		return crc64.Checksum(buf[:], h.t)
+	}
+	return crc64.Checksum(encodeKey(id), h.t)
+}
+
+// NewTypePrefixedHash returns a crc64 Hash like NewDefaultHash, but prefixes
+// the encoded key with a type tag ('i' int64, 'u' uint64, 's' string,
+// 'b' []byte), so e.g. the string "123" and []byte("123") hash differently.
+// It's used by the default strategy when Config.TypePrefixed is set.
+//
+// All keys are placed differently than with NewDefaultHash, so it must be
+// chosen when the cluster is created.
+func NewTypePrefixedHash[KeyType ID]() Hash[KeyType] {
+	return &typePrefixedHash[KeyType]{crc64.MakeTable(crc64.ISO)}
+}
+
+type typePrefixedHash[KeyType ID] struct {
+	t *crc64.Table
+}
+
+// Sum of id.
+func (h *typePrefixedHash[KeyType]) Sum(id KeyType) uint64 {
+	return crc64.Update(crc64.Update(0, h.t, []byte{keyTag(id)}), h.t, encodeKey(id))
+}
+
+// keyTag returns the type tag of an ID value, or 0 for other types.
+func keyTag(key any) byte {
+	switch key.(type) {
+	case int64:
+		return 'i'
+	case uint64:
+		return 'u'
+	case string:
+		return 's'
+	case []byte:
+		return 'b'
+	}
+	return 0
+}
+
+// CombineKeys encodes keys, each an int64, uint64, string or []byte, into
+// one []byte key, e.g. to route by a (tenant id, user name) tuple with
+// cluster.One(CombineKeys(tenantID, userName)) on a []byte cluster. Each
+// key is encoded as its type tag, length and the bytes hashed by
+// NewDefaultHash, so the result is stable, depends on the order of keys and
+// different tuples can't encode the same. It panics on other key types.
+func CombineKeys(keys ...any) []byte {
+	var res []byte
+	for _, k := range keys {
+		var b []byte
+		switch v := k.(type) {
+		case int64:
+			b = encodeKey(v)
+		case uint64:
+			b = encodeKey(v)
+		case string:
+			b = encodeKey(v)
+		case []byte:
+			b = v
+		default:
+			panic(fmt.Sprintf("sharding: CombineKeys: unsupported key type %T", k))
+		}
+		res = append(res, keyTag(k))
+		res = binary.AppendUvarint(res, uint64(len(b)))
+		res = append(res, b...)
+	}
+	return res
+}
@@ -0,0 +1,281 @@
+package sharding
+
+import (
+	"encoding/binary"
+	"hash/crc64"
+	"testing"
+)
+
+func Test_sha256Hash(t *testing.T) {
+	tests := []struct {
+		name string
+		sum  func() uint64
+		want uint64
+	}{
+		{"int64", func() uint64 { return NewSHA256Hash[int64]().Sum(123) }, 0xa665a45920422f9d},
+		{"uint64", func() uint64 { return NewSHA256Hash[uint64]().Sum(123) }, 0xa665a45920422f9d},
+		{"string", func() uint64 { return NewSHA256Hash[string]().Sum("123") }, 0xa665a45920422f9d},
+		{"[]byte", func() uint64 { return NewSHA256Hash[[]byte]().Sum([]byte("123")) }, 0xa665a45920422f9d},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sum(); got != tt.want {
+				t.Errorf("Sum() = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+// skew returns the ratio between the most and the least loaded bucket
+// when n sequential ids are distributed across shards buckets with h.
+func skew(h Hash[uint64], n uint64, shards int) float64 {
+	buckets := make([]int, shards)
+	for id := uint64(1); id <= n; id++ {
+		buckets[h.Sum(id)%uint64(shards)]++
+	}
+	lo, hi := buckets[0], buckets[0]
+	for _, b := range buckets {
+		if b < lo {
+			lo = b
+		}
+		if b > hi {
+			hi = b
+		}
+	}
+	if lo == 0 {
+		return float64(hi)
+	}
+	return float64(hi) / float64(lo)
+}
+
+func Test_sha256Hash_distribution(t *testing.T) {
+	for _, shards := range []int{2, 4, 8, 10} {
+		crc := skew(NewDefaultHash[uint64](), 10000, shards)
+		sha := skew(NewSHA256Hash[uint64](), 10000, shards)
+		if sha >= crc {
+			t.Errorf("%d shards: sha256 skew %.2f, crc64 skew %.2f, want sha256 < crc64", shards, sha, crc)
+		}
+		if sha > 1.2 {
+			t.Errorf("%d shards: sha256 skew %.2f, want <= 1.2", shards, sha)
+		}
+	}
+}
+
+func Test_xxh64(t *testing.T) {
+	tests := []struct {
+		in   string
+		want uint64
+	}{
+		{"", 0xef46db3751d8e999},
+		{"a", 0xd24ec4f1a98c6e5b},
+		{"abc", 0x44bc2cf5ad770999},
+		{"Nobody inspects the spammish repetition", 0xfbcea83c8a378bf1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := xxh64(tt.in); got != tt.want {
+				t.Errorf("xxh64(string) = %#x, want %#x", got, tt.want)
+			}
+			if got := xxh64([]byte(tt.in)); got != tt.want {
+				t.Errorf("xxh64([]byte) = %#x, want %#x", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_xxHash(t *testing.T) {
+	want := xxh64("123")
+	tests := []struct {
+		name string
+		sum  func() uint64
+	}{
+		{"int64", func() uint64 { return NewXXHash[int64]().Sum(123) }},
+		{"uint64", func() uint64 { return NewXXHash[uint64]().Sum(123) }},
+		{"string", func() uint64 { return NewXXHash[string]().Sum("123") }},
+		{"[]byte", func() uint64 { return NewXXHash[[]byte]().Sum([]byte("123")) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sum(); got != want {
+				t.Errorf("Sum() = %#x, want %#x", got, want)
+			}
+		})
+	}
+	if got := NewXXHash[int64]().Sum(-123); got != xxh64("-123") {
+		t.Errorf("Sum(-123) = %#x, want %#x", got, xxh64("-123"))
+	}
+}
+
+var benchKeys = []string{
+	"9m4e2mr0ui3e8a215n4g",
+	"user:1234567890",
+	"a-much-longer-key-which-spans-more-than-thirty-two-bytes",
+}
+
+func BenchmarkDefaultHash_string(b *testing.B) {
+	h := NewDefaultHash[string]()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.Sum(benchKeys[i%len(benchKeys)])
+	}
+}
+
+func BenchmarkXXHash_string(b *testing.B) {
+	h := NewXXHash[string]()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.Sum(benchKeys[i%len(benchKeys)])
+	}
+}
+
+func Test_defaultHashBinary(t *testing.T) {
+	tab := crc64.MakeTable(crc64.ISO)
+	le := func(v uint64) uint64 {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		return crc64.Checksum(b[:], tab)
+	}
+	tests := []struct {
+		name string
+		sum  func() uint64
+		want uint64
+	}{
+		{"int64", func() uint64 { return NewDefaultHashBinary[int64]().Sum(123) }, le(123)},
+		{"negative int64", func() uint64 { return NewDefaultHashBinary[int64]().Sum(-1) }, le(1<<64 - 1)},
+		{"uint64", func() uint64 { return NewDefaultHashBinary[uint64]().Sum(123) }, le(123)},
+		{"string", func() uint64 { return NewDefaultHashBinary[string]().Sum("123") }, 4612164443424423936},
+		{"[]byte", func() uint64 { return NewDefaultHashBinary[[]byte]().Sum([]byte("123")) }, 4612164443424423936},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sum(); got != tt.want {
+				t.Errorf("Sum() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if NewDefaultHashBinary[int64]().Sum(123) == NewDefaultHash[int64]().Sum(123) {
+		t.Error("binary and decimal encodings of 123 hash the same")
+	}
+	h := NewDefaultHashBinary[uint64]()
+	if n := testing.AllocsPerRun(100, func() { h.Sum(123) }); n != 0 {
+		t.Errorf("Sum() allocs = %v, want 0", n)
+	}
+}
+
+func Test_defaultHash_encoder(t *testing.T) {
+	type user struct {
+		tenant string
+		id     string
+	}
+	users := map[string]user{
+		"a": {"acme", "1"},
+		"b": {"acme", "2"},
+	}
+	// keys are user handles, but placement is by tenant and id.
+	enc := KeyEncoderFunc[string](func(key string) []byte {
+		u := users[key]
+		return []byte(u.tenant + "/" + u.id)
+	})
+	h := NewDefaultHash[string](enc)
+	for key, u := range users {
+		if got, want := h.Sum(key), NewDefaultHash[string]().Sum(u.tenant+"/"+u.id); got != want {
+			t.Errorf("Sum(%q) = %v, want %v", key, got, want)
+		}
+	}
+	s := NewDefaultStrategy[string, int](nil, enc)
+	shards := []Shard[int]{NewShard(1, 1), NewShard(2, 2), NewShard(3, 3)}
+	for key, u := range users {
+		want := shards[NewDefaultHash[string]().Sum(u.tenant+"/"+u.id)%3]
+		if got := s.Find(key, shards); got != want {
+			t.Errorf("Find(%q) = %v, want %v", key, got.ID(), want.ID())
+		}
+	}
+}
+
+func Test_defaultHash_allocs(t *testing.T) {
+	hi, hu, hs := NewDefaultHash[int64](), NewDefaultHash[uint64](), NewDefaultHash[string]()
+	tests := []struct {
+		name string
+		sum  func()
+	}{
+		{"int64", func() { hi.Sum(-1234567890) }},
+		{"uint64", func() { hu.Sum(1234567890) }},
+		{"string", func() { hs.Sum("9m4e2mr0ui3e8a215n4g") }},
+		{"long string", func() { hs.Sum("9m4e2mr0ui3e8a215n4g9m4e2mr0ui3e8a215n4g9m4e2mr0ui3e8a215n4g") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if n := testing.AllocsPerRun(100, tt.sum); n != 0 {
+				t.Errorf("Sum() allocs = %v, want 0", n)
+			}
+		})
+	}
+}
+
+func Test_typePrefixedHash(t *testing.T) {
+	sums := map[string]uint64{
+		"int64":  NewTypePrefixedHash[int64]().Sum(123),
+		"uint64": NewTypePrefixedHash[uint64]().Sum(123),
+		"string": NewTypePrefixedHash[string]().Sum("123"),
+		"[]byte": NewTypePrefixedHash[[]byte]().Sum([]byte("123")),
+	}
+	seen := make(map[uint64]string, len(sums))
+	for name, sum := range sums {
+		if other, ok := seen[sum]; ok {
+			t.Errorf("Sum() of %s = Sum() of %s = %d, want distinct", name, other, sum)
+		}
+		seen[sum] = name
+		if sum == 4612164443424423936 {
+			t.Errorf("Sum() of %s = default hash sum, want type prefixed", name)
+		}
+	}
+	tab := crc64.MakeTable(crc64.ISO)
+	if got, want := sums["string"], crc64.Checksum([]byte("s123"), tab); got != want {
+		t.Errorf("Sum() of string = %d, want %d", got, want)
+	}
+}
+
+func TestCombineKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []any
+		want []byte
+	}{
+		{"empty", nil, nil},
+		{"int64 and string", []any{int64(42), "bob"}, []byte("i\x0242s\x03bob")},
+		{"uint64 and []byte", []any{uint64(7), []byte{0, 1}}, []byte("u\x017b\x02\x00\x01")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CombineKeys(tt.keys...)
+			if string(got) != string(tt.want) {
+				t.Errorf("CombineKeys() = %q, want %q", got, tt.want)
+			}
+			if again := CombineKeys(tt.keys...); string(again) != string(got) {
+				t.Errorf("CombineKeys() = %q, then %q, want stable output", got, again)
+			}
+		})
+	}
+	distinct := [][]any{
+		{int64(42), "bob"},
+		{"bob", int64(42)},
+		{uint64(42), "bob"},
+		{"42", "bob"},
+		{"ab", "c"},
+		{"a", "bc"},
+	}
+	seen := make(map[string]int)
+	for i, keys := range distinct {
+		k := string(CombineKeys(keys...))
+		if j, ok := seen[k]; ok {
+			t.Errorf("CombineKeys(%v) = CombineKeys(%v) = %q, want distinct", keys, distinct[j], k)
+		}
+		seen[k] = i
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("CombineKeys(int) panic = nil, want panic")
+		}
+	}()
+	CombineKeys(42)
+}
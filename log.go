@@ -0,0 +1,12 @@
+package sharding
+
+// Logger logs connection lifecycle events. It is satisfied by *log.Logger
+// and most third party loggers. Messages never contain shard addresses,
+// only shard ids.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...any) {}
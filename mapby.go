@@ -0,0 +1,77 @@
+package sharding
+
+// MapBy groups ids by shard like Cluster.Map, and then within each shard
+// by sub(id), e.g. a table partition. Keys within each group retain their
+// relative input order. The result is empty when the cluster is.
+func MapBy[KeyType ID, ConnType any, SubKey comparable](
+	c Cluster[KeyType, ConnType],
+	ids []KeyType,
+	sub func(KeyType) SubKey,
+) map[Shard[ConnType]]map[SubKey][]KeyType {
+	shards, _ := place(c, ids)
+	res := make(map[Shard[ConnType]]map[SubKey][]KeyType)
+	for i, s := range shards {
+		m, ok := res[s]
+		if !ok {
+			m = make(map[SubKey][]KeyType)
+			res[s] = m
+		}
+		k := sub(ids[i])
+		m[k] = append(m[k], ids[i])
+	}
+	return res
+}
+
+// GroupBy groups items by shard of key(item), like Cluster.Map does for
+// raw ids, e.g. to issue one multi-row insert per shard. Items within each
+// group retain their relative input order. The result is empty when the
+// cluster is.
+func GroupBy[KeyType ID, ConnType any, T any](
+	c Cluster[KeyType, ConnType],
+	items []T,
+	key func(T) KeyType,
+) map[Shard[ConnType]][]T {
+	keys := make([]KeyType, len(items))
+	for i, item := range items {
+		keys[i] = key(item)
+	}
+	shards, _ := place(c, keys)
+	res := make(map[Shard[ConnType]][]T)
+	for i, s := range shards {
+		res[s] = append(res[s], items[i])
+	}
+	return res
+}
+
+// placer is implemented by clusters created by Connect.
+type placer[KeyType ID, ConnType any] interface {
+	place(keys []KeyType) ([]Shard[ConnType], error)
+}
+
+// place returns the shard of each of keys or ErrNoShards. Clusters created
+// by Connect place keys on their own shard list, so strategies caching by
+// the list (e.g. the consistent hash ring) aren't rebuilt on each call.
+func place[KeyType ID, ConnType any](c Cluster[KeyType, ConnType], keys []KeyType) ([]Shard[ConnType], error) {
+	if p, ok := c.(placer[KeyType, ConnType]); ok {
+		return p.place(keys)
+	}
+	return placeOn(c.Strategy(), c.All(), keys)
+}
+
+// placeOn returns the shard of each of keys on list or ErrNoShards.
+func placeOn[KeyType ID, ConnType any](
+	strategy Strategy[KeyType, ConnType],
+	list []Shard[ConnType],
+	keys []KeyType,
+) ([]Shard[ConnType], error) {
+	if len(list) == 0 && len(keys) > 0 {
+		return nil, ErrNoShards
+	}
+	res := make([]Shard[ConnType], len(keys))
+	for i, k := range keys {
+		if res[i] = strategy.Find(k, list); res[i] == nil {
+			return nil, ErrNoShards
+		}
+	}
+	return res, nil
+}
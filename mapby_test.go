@@ -0,0 +1,96 @@
+package sharding
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMapBy(t *testing.T) {
+	sh := []Shard[struct{}]{
+		&shard[struct{}]{id: 1, conn: struct{}{}},
+		&shard[struct{}]{id: 2, conn: struct{}{}},
+		&shard[struct{}]{id: 3, conn: struct{}{}},
+	}
+	c := &cluster[uint64, struct{}]{
+		list: sh,
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+	}
+	got := MapBy[uint64, struct{}](c, []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, func(id uint64) uint64 {
+		return id % 2
+	})
+	want := map[Shard[struct{}]]map[uint64][]uint64{
+		sh[0]: {0: {10}, 1: {1, 7}},
+		sh[1]: {0: {4}, 1: {9}},
+		sh[2]: {0: {2, 6, 8}, 1: {3, 5}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MapBy() = %v, want %v", got, want)
+	}
+}
+
+func TestGroupBy(t *testing.T) {
+	type record struct {
+		ID   uint64
+		Name string
+	}
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			&shard[struct{}]{id: 1, conn: struct{}{}},
+			&shard[struct{}]{id: 2, conn: struct{}{}},
+			&shard[struct{}]{id: 3, conn: struct{}{}},
+		},
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+	}
+	ids := []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	records := make([]record, len(ids))
+	for i, id := range ids {
+		records[i] = record{ID: id, Name: fmt.Sprint("record-", id)}
+	}
+	got := GroupBy[uint64, struct{}](c, records, func(r record) uint64 {
+		return r.ID
+	})
+	want := c.Map(ids)
+	if len(got) != len(want) {
+		t.Fatalf("GroupBy() returned %d groups, want %d", len(got), len(want))
+	}
+	for s, keys := range want {
+		var gotKeys []uint64
+		for _, r := range got[s] {
+			gotKeys = append(gotKeys, r.ID)
+		}
+		if !reflect.DeepEqual(gotKeys, keys) {
+			t.Errorf("GroupBy() shard %d = %v, want %v", s.ID(), gotKeys, keys)
+		}
+	}
+}
+
+// listStrategy records the shard lists passed to Find.
+type listStrategy[KeyType ID, ConnType any] struct {
+	firstStrategy[KeyType, ConnType]
+	lists map[*Shard[ConnType]]bool
+}
+
+func (s listStrategy[KeyType, ConnType]) Find(key KeyType, shards []Shard[ConnType]) Shard[ConnType] {
+	s.lists[&shards[0]] = true
+	return s.firstStrategy.Find(key, shards)
+}
+
+func Test_place_clusterList(t *testing.T) {
+	s := listStrategy[uint64, struct{}]{lists: make(map[*Shard[struct{}]]bool)}
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{NewShard(1, struct{}{}), NewShard(2, struct{}{})},
+		calc: s,
+	}
+	ids := []uint64{1, 2, 3}
+	for i := 0; i < 3; i++ {
+		MapBy[uint64, struct{}](c, ids, func(id uint64) uint64 { return id })
+		GroupBy[uint64, struct{}](c, ids, func(id uint64) uint64 { return id })
+		_, _ = Pipeline[uint64, struct{}](c, map[uint64]int{1: 1, 2: 2}, func(Shard[struct{}], map[uint64]int) (map[uint64]int, error) {
+			return nil, nil
+		})
+	}
+	if len(s.lists) != 1 || !s.lists[&c.list[0]] {
+		t.Errorf("Find() got %d shard lists, want only the cluster one", len(s.lists))
+	}
+}
@@ -0,0 +1,90 @@
+package sharding
+
+import (
+	"container/heap"
+)
+
+// MergeSorted runs fn on each shard concurrently and merges the results,
+// each of which must already be sorted by less, into one sorted slice with
+// a k-way merge. Equal items keep the shard order of Cluster.All. Errors are
+// joined, results of failed shards are omitted.
+func MergeSorted[KeyType ID, ConnType any, T any](
+	c Cluster[KeyType, ConnType],
+	fn func(Shard[ConnType]) ([]T, error),
+	less func(T, T) bool,
+) ([]T, error) {
+	var (
+		list  = activeShards(c)
+		parts = make([][]T, len(list))
+		index = make(map[Shard[ConnType]]int, len(list))
+	)
+	for i, s := range list {
+		index[s] = i
+	}
+	err := eachJoin(list, func(s Shard[ConnType]) error {
+		r, err := fn(s)
+		if err != nil {
+			return err
+		}
+		parts[index[s]] = r
+		return nil
+	})
+	h := &mergeHeap[T]{less: less}
+	n := 0
+	for i, p := range parts {
+		if len(p) > 0 {
+			h.items = append(h.items, mergeItem[T]{part: i, items: p})
+			n += len(p)
+		}
+	}
+	heap.Init(h)
+	res := make([]T, 0, n)
+	for h.Len() > 0 {
+		top := &h.items[0]
+		res = append(res, top.items[0])
+		if top.items = top.items[1:]; len(top.items) == 0 {
+			heap.Pop(h)
+		} else {
+			heap.Fix(h, 0)
+		}
+	}
+	return res, err
+}
+
+// mergeItem is the remainder of the sorted result of the shard at index
+// part in Cluster.All.
+type mergeItem[T any] struct {
+	part  int
+	items []T
+}
+
+// mergeHeap is a heap.Interface of mergeItems ordered by their first item,
+// ties broken by part.
+type mergeHeap[T any] struct {
+	items []mergeItem[T]
+	less  func(T, T) bool
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.items) }
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	a, b := h.items[i], h.items[j]
+	if h.less(a.items[0], b.items[0]) {
+		return true
+	}
+	if h.less(b.items[0], a.items[0]) {
+		return false
+	}
+	return a.part < b.part
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) { h.items[i], h.items[j] = h.items[j], h.items[i] }
+
+func (h *mergeHeap[T]) Push(x any) { h.items = append(h.items, x.(mergeItem[T])) }
+
+func (h *mergeHeap[T]) Pop() any {
+	n := len(h.items) - 1
+	x := h.items[n]
+	h.items = h.items[:n]
+	return x
+}
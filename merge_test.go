@@ -0,0 +1,97 @@
+package sharding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMergeSorted(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+	}
+	parts := map[int64][]int{
+		1: {1, 4, 7, 10},
+		2: {2, 2, 5, 8},
+		3: {3, 6, 9, 11, 12},
+	}
+	less := func(a, b int) bool {
+		return a < b
+	}
+	errFn := errors.New("error")
+	tests := []struct {
+		name    string
+		fn      func(Shard[struct{}]) ([]int, error)
+		want    []int
+		wantErr error
+	}{
+		{
+			"merge",
+			func(s Shard[struct{}]) ([]int, error) {
+				return parts[s.ID()], nil
+			},
+			[]int{1, 2, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+			nil,
+		},
+		{
+			"empty",
+			func(Shard[struct{}]) ([]int, error) {
+				return nil, nil
+			},
+			[]int{},
+			nil,
+		},
+		{
+			"one shard fails",
+			func(s Shard[struct{}]) ([]int, error) {
+				if s.ID() == 2 {
+					return nil, errFn
+				}
+				return parts[s.ID()], nil
+			},
+			[]int{1, 3, 4, 6, 7, 9, 10, 11, 12},
+			errFn,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MergeSorted[uint64, struct{}](c, tt.fn, less)
+			if !matchErr(err, tt.wantErr) {
+				t.Errorf("MergeSorted() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeSorted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeSorted_stable(t *testing.T) {
+	type row struct {
+		key   int
+		shard int64
+	}
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+	}
+	got, err := MergeSorted[uint64, struct{}](c, func(s Shard[struct{}]) ([]row, error) {
+		return []row{{1, s.ID()}, {2, s.ID()}}, nil
+	}, func(a, b row) bool {
+		return a.key < b.key
+	})
+	if err != nil {
+		t.Fatalf("MergeSorted() error = %v", err)
+	}
+	want := []row{{1, 1}, {1, 2}, {1, 3}, {2, 1}, {2, 2}, {2, 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeSorted() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package sharding
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics collects per-shard counters when set as Config.Metrics: shards
+// picked by Cluster.One and OneByHash, and calls of fn, their errors and
+// total duration in EachCtx and ByKeysCtx (and so Each and ByKeys). Counters
+// only grow, which maps to Prometheus counters. The zero value is ready to
+// use.
+type Metrics struct {
+	shards sync.Map // int64 -> *shardMetrics
+}
+
+type shardMetrics struct {
+	selections atomic.Uint64
+	calls      atomic.Uint64
+	errors     atomic.Uint64
+	duration   atomic.Int64
+}
+
+// ShardMetrics is a snapshot of the counters of a shard.
+type ShardMetrics struct {
+	Selections uint64        // times picked by Cluster.One or Cluster.OneByHash.
+	Calls      uint64        // fn calls in EachCtx and ByKeysCtx.
+	Errors     uint64        // fn calls which returned an error.
+	Duration   time.Duration // total duration of fn calls.
+}
+
+// MetricsSnapshot is a snapshot of Metrics.
+type MetricsSnapshot struct {
+	Shards map[int64]ShardMetrics
+}
+
+// Snapshot returns the current counters by shard id.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	res := MetricsSnapshot{Shards: make(map[int64]ShardMetrics)}
+	m.shards.Range(func(id, v any) bool {
+		s := v.(*shardMetrics)
+		res.Shards[id.(int64)] = ShardMetrics{
+			Selections: s.selections.Load(),
+			Calls:      s.calls.Load(),
+			Errors:     s.errors.Load(),
+			Duration:   time.Duration(s.duration.Load()),
+		}
+		return true
+	})
+	return res
+}
+
+func (m *Metrics) shard(id int64) *shardMetrics {
+	s, ok := m.shards.Load(id)
+	if !ok {
+		s, _ = m.shards.LoadOrStore(id, new(shardMetrics))
+	}
+	return s.(*shardMetrics)
+}
+
+// selected counts a pick of shard id.
+func (m *Metrics) selected(id int64) {
+	if m == nil {
+		return
+	}
+	m.shard(id).selections.Add(1)
+}
+
+// called counts a call of fn on shard id.
+func (m *Metrics) called(id int64, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	s := m.shard(id)
+	s.calls.Add(1)
+	s.duration.Add(int64(d))
+	if err != nil {
+		s.errors.Add(1)
+	}
+}
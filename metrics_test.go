@@ -0,0 +1,54 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	m := new(Metrics)
+	c, err := Connect(Config[uint64, string]{
+		Connect: func(_ context.Context, addr string) (string, error) {
+			return addr, nil
+		},
+		Shards:  []ShardConfig{{ID: 1, Addr: "1"}, {ID: 2, Addr: "2"}, {ID: 3, Addr: "3"}},
+		Metrics: m,
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if got := m.Snapshot().Shards; len(got) != 0 {
+		t.Errorf("Snapshot() = %v, want empty", got)
+	}
+	for key := uint64(1); key <= 10; key++ {
+		c.One(key)
+	}
+	c.OneByHash(0)
+	_ = c.Each(func(s Shard[string]) error {
+		if s.ID() == 2 {
+			return errors.New("error")
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	_ = c.ByKeys([]uint64{1, 7}, func([]uint64, Shard[string]) error {
+		return nil
+	})
+	got := m.Snapshot().Shards
+	want := map[int64]ShardMetrics{
+		1: {Selections: 3 + 1, Calls: 2, Errors: 0},
+		2: {Selections: 2, Calls: 1, Errors: 1},
+		3: {Selections: 5, Calls: 1, Errors: 0},
+	}
+	for id, w := range want {
+		g := got[id]
+		if g.Selections != w.Selections || g.Calls != w.Calls || g.Errors != w.Errors {
+			t.Errorf("Snapshot() shard %d = %+v, want %+v", id, g, w)
+		}
+	}
+	if d := got[3].Duration; d < time.Millisecond {
+		t.Errorf("Snapshot() shard 3 duration = %s, want >= 1ms", d)
+	}
+}
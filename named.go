@@ -0,0 +1,82 @@
+package sharding
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc64"
+	"sort"
+)
+
+// NamedShardConfig configures a shard identified by name, e.g. "us-east-1a",
+// rather than by number. See ConnectNamed.
+type NamedShardConfig struct {
+	Name string `json:"name"`
+	Addr string `json:"dsn"`
+}
+
+// NamedShard is a Shard which has a name. All shards created by Connect
+// implement it, but only shards created by ConnectNamed have a non-empty
+// name.
+type NamedShard[ConnType any] interface {
+	Shard[ConnType]
+	Name() string
+}
+
+// ConnectNamed connects to named shards like Connect does, cfg.Shards must
+// be empty. Shard ids are derived from a hash of the names, so a shard keeps
+// its id when other names are added or removed, and shards are ordered by
+// name unless cfg.ShardLess is set. Key placement is stable for a given set
+// of names. Shards of the returned cluster implement NamedShard.
+func ConnectNamed[KeyType ID, ConnType any](
+	cfg Config[KeyType, ConnType],
+	shards []NamedShardConfig,
+) (Cluster[KeyType, ConnType], error) {
+	if len(cfg.Shards) > 0 {
+		return nil, errors.New("shards must be passed as named shards only")
+	}
+	sorted := make([]NamedShardConfig, len(shards))
+	copy(sorted, shards)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	var (
+		t     = crc64.MakeTable(crc64.ISO)
+		names = make(map[int64]string, len(sorted))
+	)
+	cfg.Shards = make([]ShardConfig, len(sorted))
+	for i, sc := range sorted {
+		if sc.Name == "" {
+			return nil, fmt.Errorf("validation: empty shard name for %s", redact(sc.Addr))
+		}
+		if i > 0 && sorted[i-1].Name == sc.Name {
+			return nil, fmt.Errorf("validation: duplicate shard name %q", sc.Name)
+		}
+		id := namedShardID(sc.Name, t)
+		if other, ok := names[id]; ok {
+			return nil, fmt.Errorf("validation: shard names %q and %q hash to the same id", other, sc.Name)
+		}
+		names[id] = sc.Name
+		cfg.Shards[i] = ShardConfig{ID: id, Addr: sc.Addr, name: sc.Name}
+	}
+	if cfg.ShardLess == nil {
+		cfg.ShardLess = func(a, b ShardConfig) bool {
+			return a.name < b.name
+		}
+	}
+	return Connect(cfg)
+}
+
+// namedShardID returns a positive id of the shard named name.
+func namedShardID(name string, t *crc64.Table) int64 {
+	return int64(crc64.Checksum([]byte(name), t)>>1) | 1
+}
+
+// Name returns the shard name.
+func (s *shard[ConnType]) Name() string {
+	return s.name
+}
+
+// Name returns the shard name.
+func (s *lazyShard[ConnType]) Name() string {
+	return s.name
+}
@@ -0,0 +1,128 @@
+package sharding
+
+import (
+	"context"
+	"hash/crc64"
+	"reflect"
+	"testing"
+)
+
+func TestConnectNamed(t *testing.T) {
+	connect := func(_ context.Context, addr string) (string, error) {
+		return "conn-" + addr, nil
+	}
+	tests := []struct {
+		name    string
+		cfg     Config[string, string]
+		shards  []NamedShardConfig
+		want    []string
+		wantErr bool
+	}{
+		{
+			"sorted by name",
+			Config[string, string]{Connect: connect},
+			[]NamedShardConfig{
+				{Name: "us-west-1a", Addr: "3"},
+				{Name: "eu-central-1a", Addr: "1"},
+				{Name: "us-east-1a", Addr: "2"},
+			},
+			[]string{"eu-central-1a", "us-east-1a", "us-west-1a"},
+			false,
+		},
+		{
+			"lazy",
+			Config[string, string]{Connect: connect, Lazy: true},
+			[]NamedShardConfig{
+				{Name: "b", Addr: "2"},
+				{Name: "a", Addr: "1"},
+			},
+			[]string{"a", "b"},
+			false,
+		},
+		{
+			"empty name",
+			Config[string, string]{Connect: connect},
+			[]NamedShardConfig{{Name: "a", Addr: "1"}, {Addr: "2"}},
+			nil,
+			true,
+		},
+		{
+			"duplicate name",
+			Config[string, string]{Connect: connect},
+			[]NamedShardConfig{{Name: "a", Addr: "1"}, {Name: "a", Addr: "2"}},
+			nil,
+			true,
+		},
+		{
+			"numbered shards",
+			Config[string, string]{Connect: connect, Shards: []ShardConfig{{ID: 1, Addr: "1"}}},
+			[]NamedShardConfig{{Name: "a", Addr: "2"}},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ConnectNamed(tt.cfg, tt.shards)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectNamed() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var got []string
+			for _, s := range c.All() {
+				ns, ok := s.(NamedShard[string])
+				if !ok {
+					t.Fatalf("shard %d is not a NamedShard", s.ID())
+				}
+				if want := namedShardID(ns.Name(), crc64.MakeTable(crc64.ISO)); s.ID() != want {
+					t.Errorf("shard %q id = %d, want %d", ns.Name(), s.ID(), want)
+				}
+				if want := "conn-" + ns.Addr(); s.Conn() != want {
+					t.Errorf("shard %q conn = %q, want %q", ns.Name(), s.Conn(), want)
+				}
+				got = append(got, ns.Name())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConnectNamed() names = %v, want %v", got, tt.want)
+			}
+			for _, key := range []string{"a", "b", "c", "d"} {
+				if name := c.One(key).(NamedShard[string]).Name(); name == "" {
+					t.Errorf("One(%q) returned a shard without name", key)
+				}
+			}
+			if err = c.Reconnect(context.Background(), c.All()[0].ID()); err != nil {
+				t.Fatalf("Reconnect() error = %v", err)
+			}
+			if name := c.All()[0].(NamedShard[string]).Name(); name != tt.want[0] {
+				t.Errorf("Reconnect() shard name = %q, want %q", name, tt.want[0])
+			}
+		})
+	}
+}
+
+func TestConnectNamed_stableIDs(t *testing.T) {
+	connect := func(_ context.Context, addr string) (string, error) {
+		return addr, nil
+	}
+	ids := func(shards ...NamedShardConfig) map[string]int64 {
+		t.Helper()
+		c, err := ConnectNamed(Config[string, string]{Connect: connect}, shards)
+		if err != nil {
+			t.Fatalf("ConnectNamed() error = %v", err)
+		}
+		res := make(map[string]int64)
+		for _, s := range c.All() {
+			res[s.(NamedShard[string]).Name()] = s.ID()
+		}
+		return res
+	}
+	before := ids(NamedShardConfig{Name: "b", Addr: "b"}, NamedShardConfig{Name: "c", Addr: "c"})
+	after := ids(NamedShardConfig{Name: "a", Addr: "a"}, NamedShardConfig{Name: "b", Addr: "b"}, NamedShardConfig{Name: "c", Addr: "c"})
+	for name, id := range before {
+		if after[name] != id {
+			t.Errorf("shard %q id = %d after adding a name, want %d", name, after[name], id)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !sharding_debug
+
+package sharding
+
+const debug = false
+
+func checkSorted[ConnType any](_ []Shard[ConnType]) {}
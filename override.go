@@ -0,0 +1,73 @@
+package sharding
+
+import "fmt"
+
+// NewOverrideStrategy returns Strategy which places keys found in
+// overrides on the shard with the given id, e.g. tenants pinned to a legacy
+// location, and all other keys with base, which may be any Strategy. Keys
+// whose shard isn't in the list passed to Find are placed with base too.
+// []byte keys are not supported as they can't be map keys.
+func NewOverrideStrategy[KeyType interface {
+	ID
+	comparable
+}, ConnType any](
+	base Strategy[KeyType, ConnType],
+	overrides map[KeyType]int64,
+) Strategy[KeyType, ConnType] {
+	pins := make(map[KeyType]int64, len(overrides))
+	for k, id := range overrides {
+		pins[k] = id
+	}
+	return overrideStrategy[KeyType, ConnType]{
+		Strategy: base,
+		pin: func(key KeyType) (int64, bool) {
+			id, ok := pins[key]
+			return id, ok
+		},
+	}
+}
+
+// withOverrides wraps s with pins by the fmt.Sprint form of the key, see
+// Config.Overrides, unless there are none.
+func withOverrides[KeyType ID, ConnType any](
+	s Strategy[KeyType, ConnType],
+	pins map[string]int64,
+) Strategy[KeyType, ConnType] {
+	if len(pins) == 0 {
+		return s
+	}
+	return overrideStrategy[KeyType, ConnType]{
+		Strategy: s,
+		pin: func(key KeyType) (int64, bool) {
+			id, ok := pins[fmt.Sprint(key)]
+			return id, ok
+		},
+	}
+}
+
+type overrideStrategy[KeyType ID, ConnType any] struct {
+	Strategy[KeyType, ConnType]
+	pin func(KeyType) (int64, bool)
+}
+
+// Find returns the shard key is pinned to, or the one picked by the
+// wrapped Strategy.
+func (o overrideStrategy[KeyType, ConnType]) Find(key KeyType, shards []Shard[ConnType]) Shard[ConnType] {
+	if id, ok := o.pin(key); ok {
+		for _, s := range shards {
+			if s.ID() == id {
+				return s
+			}
+		}
+	}
+	return o.Strategy.Find(key, shards)
+}
+
+// HashOf returns the hash of key by the wrapped Strategy, or 0 when it
+// doesn't implement KeyHasher. Pinned keys aren't placed by it.
+func (o overrideStrategy[KeyType, ConnType]) HashOf(key KeyType) uint64 {
+	if h, ok := o.Strategy.(KeyHasher[KeyType]); ok {
+		return h.HashOf(key)
+	}
+	return 0
+}
@@ -0,0 +1,43 @@
+package sharding
+
+import "testing"
+
+func Test_overrideStrategy(t *testing.T) {
+	shards := []Shard[struct{}]{
+		NewShard(1, struct{}{}),
+		NewShard(2, struct{}{}),
+		NewShard(3, struct{}{}),
+	}
+	overrides := map[uint64]int64{2: 1, 5: 42}
+	s := NewOverrideStrategy[uint64, struct{}](NewDefaultStrategy[uint64, struct{}](nil), overrides)
+	overrides[3] = 1 // changes after creation don't apply
+	tests := []struct {
+		name string
+		key  uint64
+		want int64
+	}{
+		{"pinned", 2, 1},
+		{"pinned to a missing shard", 5, 3},
+		{"not pinned", 3, 3},
+		{"not pinned too", 9, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Find(tt.key, shards).ID(); got != tt.want {
+				t.Errorf("Find(%d) = shard %d, want %d", tt.key, got, tt.want)
+			}
+		})
+	}
+	if got := s.FindByHash(0, shards).ID(); got != 1 {
+		t.Errorf("FindByHash(0) = shard %d, want 1", got)
+	}
+
+	c := NewConsistentStrategy[uint64, struct{}](nil, ConsistentOptions{})
+	s = NewOverrideStrategy[uint64, struct{}](c, map[uint64]int64{2: 2})
+	if got := s.Find(2, shards).ID(); got != 2 {
+		t.Errorf("Find(2) over consistent strategy = shard %d, want 2", got)
+	}
+	if got, want := s.Find(3, shards), c.Find(3, shards); got != want {
+		t.Errorf("Find(3) over consistent strategy = shard %d, want %d", got.ID(), want.ID())
+	}
+}
@@ -0,0 +1,56 @@
+package sharding
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// NewRangeStrategy returns Strategy which places integer keys by explicit
+// ranges: with shards in the order they're passed to Find (Cluster.All
+// order, see Config.ShardLess), keys below bounds[0] go to the first shard,
+// keys in [bounds[i-1], bounds[i]) to shard i and keys from the last bound
+// up to the last shard. E.g. bounds [1000, 2000] place [0, 1000) on the
+// first of three shards and [1000, 2000) on the second. It panics when
+// bounds aren't strictly ascending, Find panics when a non empty list
+// doesn't have exactly one more shard than bounds. FindByHash places sum as a key.
+func NewRangeStrategy[KeyType interface{ int64 | uint64 }, ConnType any](bounds []int64) Strategy[KeyType, ConnType] {
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			panic(fmt.Sprintf("sharding: range strategy bounds must be strictly ascending, got %v", bounds))
+		}
+	}
+	return rangeStrategy[KeyType, ConnType]{bounds: slices.Clone(bounds)}
+}
+
+type rangeStrategy[KeyType interface{ int64 | uint64 }, ConnType any] struct {
+	bounds []int64
+}
+
+// Find picks the shard whose range contains key.
+func (r rangeStrategy[KeyType, ConnType]) Find(key KeyType, shards []Shard[ConnType]) Shard[ConnType] {
+	if len(shards) == 0 {
+		return nil
+	}
+	if len(shards) != len(r.bounds)+1 {
+		panic(fmt.Sprintf("sharding: range strategy has %d bounds for %d shards, want shards-1", len(r.bounds), len(shards)))
+	}
+	var i int
+	switch k := any(key).(type) {
+	case int64:
+		i = sort.Search(len(r.bounds), func(i int) bool { return k < r.bounds[i] })
+	case uint64:
+		if k > math.MaxInt64 {
+			i = len(r.bounds)
+		} else {
+			i = sort.Search(len(r.bounds), func(i int) bool { return int64(k) < r.bounds[i] })
+		}
+	}
+	return shards[i]
+}
+
+// FindByHash picks the shard whose range contains sum.
+func (r rangeStrategy[KeyType, ConnType]) FindByHash(sum uint64, shards []Shard[ConnType]) Shard[ConnType] {
+	return rangeStrategy[uint64, ConnType](r).Find(sum, shards)
+}
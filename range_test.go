@@ -0,0 +1,71 @@
+package sharding
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRangeStrategy(t *testing.T) {
+	shards := []Shard[struct{}]{
+		NewShard(1, struct{}{}),
+		NewShard(2, struct{}{}),
+		NewShard(3, struct{}{}),
+	}
+	s := NewRangeStrategy[int64, struct{}]([]int64{1000, 2000})
+	tests := []struct {
+		name string
+		key  int64
+		want int64
+	}{
+		{"first range start", 0, 1},
+		{"first range end", 999, 1},
+		{"on first bound", 1000, 2},
+		{"second range end", 1999, 2},
+		{"on last bound", 2000, 3},
+		{"below all ranges", -5, 1},
+		{"above all ranges", math.MaxInt64, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Find(tt.key, shards).ID(); got != tt.want {
+				t.Errorf("Find(%d) = shard %d, want %d", tt.key, got, tt.want)
+			}
+		})
+	}
+
+	u := NewRangeStrategy[uint64, struct{}]([]int64{1000, 2000})
+	for key, want := range map[uint64]int64{999: 1, 1000: 2, 2000: 3, math.MaxUint64: 3} {
+		if got := u.Find(key, shards).ID(); got != want {
+			t.Errorf("Find(%d) = shard %d, want %d", key, got, want)
+		}
+		if got := u.FindByHash(key, shards).ID(); got != want {
+			t.Errorf("FindByHash(%d) = shard %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestRangeStrategy_panics(t *testing.T) {
+	shards := []Shard[struct{}]{
+		NewShard(1, struct{}{}),
+		NewShard(2, struct{}{}),
+	}
+	tests := []struct {
+		name string
+		fn   func()
+	}{
+		{"unsorted bounds", func() { NewRangeStrategy[int64, struct{}]([]int64{2000, 1000}) }},
+		{"duplicate bounds", func() { NewRangeStrategy[int64, struct{}]([]int64{1000, 1000}) }},
+		{"too many bounds", func() { NewRangeStrategy[int64, struct{}]([]int64{1000, 2000}).Find(1, shards) }},
+		{"too few bounds", func() { NewRangeStrategy[int64, struct{}](nil).Find(1, shards) }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Error("panic = nil, want panic")
+				}
+			}()
+			tt.fn()
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package sharding
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const redacted = "xxxxx"
+
+var (
+	// user:password@ prefix of DSNs that are not valid URLs, e.g. MySQL's
+	// user:password@tcp(host:3306)/db.
+	userInfoRe = regexp.MustCompile(`^([^:@/\s]+):[^@\s]*@`)
+	// password=value pairs of key/value DSNs, e.g. libpq's
+	// host=localhost user=postgres password=secret.
+	keyValueRe = regexp.MustCompile(`(?i)\b(password|passwd|pwd|pass)=('[^']*'|\S*)`)
+)
+
+var sensitiveParams = []string{"password", "passwd", "pwd", "pass"}
+
+// redact masks credentials in a shard address so it can safely appear in
+// errors and logs. Addresses without credentials, e.g. 127.0.0.1:11211,
+// are returned unchanged.
+func redact(addr string) string {
+	if u, err := url.Parse(addr); err == nil && u.Scheme != "" && u.Opaque == "" {
+		changed := false
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+			changed = true
+		}
+		if u.RawQuery != "" {
+			q := u.Query()
+			for k := range q {
+				if isSensitiveParam(k) {
+					q.Set(k, redacted)
+					changed = true
+				}
+			}
+			if changed {
+				u.RawQuery = q.Encode()
+			}
+		}
+		if !changed {
+			return addr
+		}
+		return u.String()
+	}
+	addr = userInfoRe.ReplaceAllString(addr, "$1:"+redacted+"@")
+	return keyValueRe.ReplaceAllString(addr, "$1="+redacted)
+}
+
+func isSensitiveParam(key string) bool {
+	for _, p := range sensitiveParams {
+		if strings.EqualFold(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedError hides addr in the message of the wrapped error.
+type redactedError struct {
+	err    error
+	addr   string
+	masked string
+}
+
+func newRedactedError(err error, addr string) error {
+	return &redactedError{err, addr, redact(addr)}
+}
+
+func (e *redactedError) Error() string {
+	if e.addr == "" || e.addr == e.masked {
+		return e.err.Error()
+	}
+	return strings.ReplaceAll(e.err.Error(), e.addr, e.masked)
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,78 @@
+package sharding
+
+import "sort"
+
+// NewRendezvousStrategy returns Strategy which picks the shard with the
+// highest rendezvous (HRW) weight for the key. Like NewConsistentStrategy,
+// removing a shard only moves keys placed on it, but no ring has to be
+// kept in memory, at the cost of hashing every shard on each Find. When
+// hash is nil, NewDefaultHash is used, which places keys on the first
+// shard returned by Cluster.ReplicasHRW.
+func NewRendezvousStrategy[KeyType ID, ConnType any](hash Hash[KeyType]) Strategy[KeyType, ConnType] {
+	if hash == nil {
+		hash = NewDefaultHash[KeyType]()
+	}
+	return rendezvousStrategy[KeyType, ConnType]{hash}
+}
+
+type rendezvousStrategy[KeyType ID, ConnType any] struct {
+	hash Hash[KeyType]
+}
+
+// Find picks the shard with the highest weight for key.
+func (r rendezvousStrategy[KeyType, ConnType]) Find(key KeyType, shards []Shard[ConnType]) Shard[ConnType] {
+	return r.FindByHash(r.hash.Sum(key), shards)
+}
+
+// HashOf returns the hash of key.
+func (r rendezvousStrategy[KeyType, ConnType]) HashOf(key KeyType) uint64 {
+	return r.hash.Sum(key)
+}
+
+// FindByHash picks the shard with the highest weight for sum.
+func (r rendezvousStrategy[KeyType, ConnType]) FindByHash(sum uint64, shards []Shard[ConnType]) Shard[ConnType] {
+	var (
+		best Shard[ConnType]
+		top  uint64
+	)
+	for _, s := range shards {
+		if w := hrwWeight(sum, s.ID()); best == nil || hrwLess(best.ID(), top, s.ID(), w) {
+			best, top = s, w
+		}
+	}
+	return best
+}
+
+// hrwTop returns k shards with the highest weights for sum, heaviest first.
+func hrwTop[ConnType any](sum uint64, shards []Shard[ConnType], k int) []Shard[ConnType] {
+	if k > len(shards) {
+		k = len(shards)
+	}
+	if k < 1 {
+		return nil
+	}
+	weights := make(map[Shard[ConnType]]uint64, len(shards))
+	res := make([]Shard[ConnType], len(shards))
+	for i, s := range shards {
+		weights[s] = hrwWeight(sum, s.ID())
+		res[i] = s
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return hrwLess(res[j].ID(), weights[res[j]], res[i].ID(), weights[res[i]])
+	})
+	return res[:k]
+}
+
+// hrwWeight is the rendezvous weight of shard id for a key hashing to sum.
+func hrwWeight(sum uint64, id int64) uint64 {
+	return mix64(sum ^ mix64(uint64(id)))
+}
+
+// hrwLess reports whether shard a with weight wa ranks below shard b with
+// weight wb. Equal weights are ranked by id, lower id first.
+func hrwLess(a int64, wa uint64, b int64, wb uint64) bool {
+	if wa != wb {
+		return wa < wb
+	}
+	return a > b
+}
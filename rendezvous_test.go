@@ -0,0 +1,55 @@
+package sharding
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func Test_cluster_ReplicasHRW(t *testing.T) {
+	newCluster := func(ids ...int64) *cluster[string, struct{}] {
+		c := &cluster[string, struct{}]{calc: NewRendezvousStrategy[string, struct{}](nil)}
+		for _, id := range ids {
+			c.list = append(c.list, NewShard(id, struct{}{}))
+		}
+		return c
+	}
+	ids := func(shards []Shard[struct{}]) []int64 {
+		res := make([]int64, len(shards))
+		for i, s := range shards {
+			res[i] = s.ID()
+		}
+		return res
+	}
+	c := newCluster(1, 2, 3, 4, 5, 6)
+	for i := 0; i < 100; i++ {
+		key := "key" + strconv.Itoa(i)
+		set := ids(c.ReplicasHRW(key, 3))
+		if len(set) != 3 {
+			t.Fatalf("ReplicasHRW(%q, 3) = %v, want 3 shards", key, set)
+		}
+		if got := c.One(key).ID(); got != set[0] {
+			t.Errorf("One(%q) = %d, want first replica %d", key, got, set[0])
+		}
+		// remove a shard which is not in the replica set
+		var rest []int64
+		removed := false
+		for _, id := range []int64{6, 5, 4, 3, 2, 1} {
+			member := id == set[0] || id == set[1] || id == set[2]
+			if !member && !removed {
+				removed = true
+				continue
+			}
+			rest = append(rest, id)
+		}
+		if got := ids(newCluster(rest...).ReplicasHRW(key, 3)); !reflect.DeepEqual(got, set) {
+			t.Errorf("ReplicasHRW(%q, 3) = %v after removing a non-member, want %v", key, got, set)
+		}
+	}
+	if got := c.ReplicasHRW("key", 10); len(got) != 6 {
+		t.Errorf("ReplicasHRW(key, 10) returned %d shards, want 6", len(got))
+	}
+	if got := c.ReplicasHRW("key", 0); got != nil {
+		t.Errorf("ReplicasHRW(key, 0) = %v, want nil", got)
+	}
+}
@@ -0,0 +1,46 @@
+package sharding
+
+import (
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+)
+
+// sampler counts a random sample of shards picked by Cluster.One, see
+// Config.SampleRate.
+type sampler struct {
+	rate   float64
+	counts sync.Map // int64 -> *atomic.Uint64
+}
+
+func newSampler(rate float64) *sampler {
+	if rate <= 0 {
+		return nil
+	}
+	return &sampler{rate: rate}
+}
+
+// record counts a pick of shard id with probability rate.
+func (s *sampler) record(id int64) {
+	if s == nil || s.rate < 1 && rand.Float64() >= s.rate {
+		return
+	}
+	n, ok := s.counts.Load(id)
+	if !ok {
+		n, _ = s.counts.LoadOrStore(id, new(atomic.Uint64))
+	}
+	n.(*atomic.Uint64).Add(1)
+}
+
+// snapshot returns the current counts by shard id.
+func (s *sampler) snapshot() map[int64]uint64 {
+	res := make(map[int64]uint64)
+	if s == nil {
+		return res
+	}
+	s.counts.Range(func(id, n any) bool {
+		res[id.(int64)] = n.(*atomic.Uint64).Load()
+		return true
+	})
+	return res
+}
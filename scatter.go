@@ -0,0 +1,163 @@
+package sharding
+
+import (
+	"sync"
+)
+
+// Scatter runs fn on each shard concurrently and returns the results keyed
+// by shard id. Errors are joined, results of failed shards are omitted.
+func Scatter[KeyType ID, ConnType any, R any](
+	c Cluster[KeyType, ConnType],
+	fn func(Shard[ConnType]) (R, error),
+) (map[int64]R, error) {
+	var (
+		mu  sync.Mutex
+		res = make(map[int64]R, c.Len())
+	)
+	err := eachJoin(activeShards(c), func(s Shard[ConnType]) error {
+		r, err := fn(s)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		res[s.ID()] = r
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
+
+// Reduce runs fn on each shard concurrently and folds the results with
+// combine starting from zero, e.g. to sum per-shard counts. Errors are
+// joined, results of failed shards are left out of the fold.
+func Reduce[KeyType ID, ConnType any, R any](
+	c Cluster[KeyType, ConnType],
+	fn func(Shard[ConnType]) (R, error),
+	combine func(R, R) R,
+	zero R,
+) (R, error) {
+	var (
+		mu  sync.Mutex
+		res = zero
+	)
+	err := eachJoin(activeShards(c), func(s Shard[ConnType]) error {
+		r, err := fn(s)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		res = combine(res, r)
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
+
+// Gather groups ids by shard with Cluster.Map, runs fn on each group
+// concurrently and returns the results keyed by shard id. Errors are
+// joined, results of failed shards are omitted.
+func Gather[KeyType ID, ConnType any, R any](
+	c Cluster[KeyType, ConnType],
+	ids []KeyType,
+	fn func([]KeyType, Shard[ConnType]) (R, error),
+) (map[int64]R, error) {
+	var (
+		m    = c.Map(ids)
+		list = make([]Shard[ConnType], 0, len(m))
+		mu   sync.Mutex
+		res  = make(map[int64]R, len(m))
+	)
+	if len(m) == 0 && len(ids) > 0 {
+		return res, ErrNoShards
+	}
+	for s := range m {
+		list = append(list, s)
+	}
+	err := eachJoin(list, func(s Shard[ConnType]) error {
+		r, err := fn(m[s], s)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		res[s.ID()] = r
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
+
+// ByGroups groups items by shard with GroupBy and runs fn on each group
+// concurrently, e.g. to issue one multi-row insert per shard. Errors are
+// joined.
+func ByGroups[KeyType ID, ConnType any, T any](
+	c Cluster[KeyType, ConnType],
+	items []T,
+	key func(T) KeyType,
+	fn func(Shard[ConnType], []T) error,
+) error {
+	var (
+		m    = GroupBy(c, items, key)
+		list = make([]Shard[ConnType], 0, len(m))
+	)
+	if len(m) == 0 && len(items) > 0 {
+		return ErrNoShards
+	}
+	for s := range m {
+		list = append(list, s)
+	}
+	return eachJoin(list, func(s Shard[ConnType]) error {
+		return fn(s, m[s])
+	})
+}
+
+// Pipeline groups cmds by shard of their key, runs exec on each group
+// concurrently, e.g. as one Redis pipeline per shard, and merges the results
+// keyed by the original key. Errors are joined, results of failed shards are
+// omitted. []byte keys are not supported as they can't be map keys.
+func Pipeline[KeyType interface {
+	ID
+	comparable
+}, ConnType any, Cmd any, Res any](
+	c Cluster[KeyType, ConnType],
+	cmds map[KeyType]Cmd,
+	exec func(Shard[ConnType], map[KeyType]Cmd) (map[KeyType]Res, error),
+) (map[KeyType]Res, error) {
+	keys := make([]KeyType, 0, len(cmds))
+	for k := range cmds {
+		keys = append(keys, k)
+	}
+	placed, err := place(c, keys)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[Shard[ConnType]]map[KeyType]Cmd)
+	for i, s := range placed {
+		m, ok := groups[s]
+		if !ok {
+			m = make(map[KeyType]Cmd)
+			groups[s] = m
+		}
+		m[keys[i]] = cmds[keys[i]]
+	}
+	var (
+		shards = make([]Shard[ConnType], 0, len(groups))
+		mu     sync.Mutex
+		res    = make(map[KeyType]Res, len(cmds))
+	)
+	for s := range groups {
+		shards = append(shards, s)
+	}
+	err = eachJoin(shards, func(s Shard[ConnType]) error {
+		r, err := exec(s, groups[s])
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for k, v := range r {
+			res[k] = v
+		}
+		mu.Unlock()
+		return nil
+	})
+	return res, err
+}
@@ -0,0 +1,244 @@
+package sharding
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestScatter(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+	}
+	got, err := Scatter[uint64, struct{}](c, func(s Shard[struct{}]) (int64, error) {
+		return s.ID() * 2, nil
+	})
+	if err != nil {
+		t.Errorf("Scatter() error = %v", err)
+	}
+	if want := map[int64]int64{1: 2, 2: 4, 3: 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scatter() = %v, want %v", got, want)
+	}
+
+	errFn := errors.New("error")
+	got, err = Scatter[uint64, struct{}](c, func(s Shard[struct{}]) (int64, error) {
+		if s.ID() == 2 {
+			return 0, errFn
+		}
+		return s.ID() * 2, nil
+	})
+	var se *ShardError
+	if !errors.As(err, &se) || se.ID != 2 || !errors.Is(err, errFn) {
+		t.Errorf("Scatter() error = %v, want shard 2 error", err)
+	}
+	if want := map[int64]int64{1: 2, 3: 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scatter() = %v, want %v", got, want)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+	}
+	sum := func(a, b int64) int64 {
+		return a + b
+	}
+	got, err := Reduce[uint64, struct{}](c, func(Shard[struct{}]) (int64, error) {
+		return 10, nil
+	}, sum, 0)
+	if err != nil {
+		t.Errorf("Reduce() error = %v", err)
+	}
+	if got != 30 {
+		t.Errorf("Reduce() = %d, want 30", got)
+	}
+
+	errFn := errors.New("error")
+	got, err = Reduce[uint64, struct{}](c, func(s Shard[struct{}]) (int64, error) {
+		if s.ID() == 2 {
+			return 0, errFn
+		}
+		return 10, nil
+	}, sum, 5)
+	var se *ShardError
+	if !errors.As(err, &se) || se.ID != 2 || !errors.Is(err, errFn) {
+		t.Errorf("Reduce() error = %v, want shard 2 error", err)
+	}
+	if got != 25 {
+		t.Errorf("Reduce() = %d, want 25", got)
+	}
+}
+
+func TestGather(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+	}
+	sum := func(ids []uint64, _ Shard[struct{}]) (uint64, error) {
+		var n uint64
+		for _, id := range ids {
+			n += id
+		}
+		return n, nil
+	}
+	tests := []struct {
+		name    string
+		ids     []uint64
+		fn      func([]uint64, Shard[struct{}]) (uint64, error)
+		want    map[int64]uint64
+		wantErr bool
+	}{
+		{"empty", nil, sum, map[int64]uint64{}, false},
+		{
+			"spread",
+			[]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			sum,
+			map[int64]uint64{1: 1 + 7 + 10, 2: 4 + 9, 3: 2 + 3 + 5 + 6 + 8},
+			false,
+		},
+		{
+			"one shard fails",
+			[]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10},
+			func(ids []uint64, s Shard[struct{}]) (uint64, error) {
+				if s.ID() == 3 {
+					return 0, errors.New("error")
+				}
+				return sum(ids, s)
+			},
+			map[int64]uint64{1: 1 + 7 + 10, 2: 4 + 9},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Gather[uint64, struct{}](c, tt.ids, tt.fn)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Gather() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Gather() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestByGroups(t *testing.T) {
+	type record struct {
+		ID uint64
+	}
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+	}
+	records := make([]record, 10)
+	for i := range records {
+		records[i] = record{ID: uint64(i + 1)}
+	}
+	key := func(r record) uint64 {
+		return r.ID
+	}
+	var (
+		mu       sync.Mutex
+		received = make(map[uint64]int)
+	)
+	err := ByGroups[uint64, struct{}](c, records, key, func(s Shard[struct{}], items []record) error {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, r := range items {
+			if got := c.One(r.ID); got != s {
+				t.Errorf("ByGroups() record %d delivered to shard %d, want %d", r.ID, s.ID(), got.ID())
+			}
+			received[r.ID]++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Errorf("ByGroups() error = %v", err)
+	}
+	for _, r := range records {
+		if n := received[r.ID]; n != 1 {
+			t.Errorf("ByGroups() record %d delivered %d times, want 1", r.ID, n)
+		}
+	}
+
+	errFn := errors.New("error")
+	err = ByGroups[uint64, struct{}](c, records, key, func(s Shard[struct{}], _ []record) error {
+		if s.ID() == 2 {
+			return errFn
+		}
+		return nil
+	})
+	var se *ShardError
+	if !errors.As(err, &se) || se.ID != 2 || !errors.Is(err, errFn) {
+		t.Errorf("ByGroups() error = %v, want shard 2 error", err)
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	c := &cluster[uint64, struct{}]{
+		list: []Shard[struct{}]{
+			NewShard(1, struct{}{}),
+			NewShard(2, struct{}{}),
+			NewShard(3, struct{}{}),
+		},
+		calc: NewDefaultStrategy[uint64, struct{}](nil),
+	}
+	cmds := make(map[uint64]string, 10)
+	for i := uint64(1); i <= 10; i++ {
+		cmds[i] = "get " + strconv.FormatUint(i, 10)
+	}
+	echo := func(s Shard[struct{}], cmds map[uint64]string) (map[uint64]string, error) {
+		res := make(map[uint64]string, len(cmds))
+		for k, cmd := range cmds {
+			if got := c.One(k); got != s {
+				t.Errorf("Pipeline() key %d executed on shard %d, want %d", k, s.ID(), got.ID())
+			}
+			res[k] = cmd
+		}
+		return res, nil
+	}
+	got, err := Pipeline[uint64, struct{}](c, cmds, echo)
+	if err != nil {
+		t.Errorf("Pipeline() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, cmds) {
+		t.Errorf("Pipeline() = %v, want %v", got, cmds)
+	}
+
+	errFn := errors.New("error")
+	got, err = Pipeline[uint64, struct{}](c, cmds, func(s Shard[struct{}], cmds map[uint64]string) (map[uint64]string, error) {
+		if s.ID() == 3 {
+			return nil, errFn
+		}
+		return echo(s, cmds)
+	})
+	var se *ShardError
+	if !errors.As(err, &se) || se.ID != 3 || !errors.Is(err, errFn) {
+		t.Errorf("Pipeline() error = %v, want shard 3 error", err)
+	}
+	want := make(map[uint64]string)
+	for _, k := range []uint64{1, 7, 10, 4, 9} {
+		want[k] = cmds[k]
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pipeline() = %v, want %v", got, want)
+	}
+}
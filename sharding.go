@@ -1,27 +1,51 @@
 package sharding
 
 import (
+	"cmp"
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"hash/crc64"
+	"io"
+	"iter"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // Connect to database using configs.
 func Connect[KeyType ID, ConnType any](cfg Config[KeyType, ConnType]) (Cluster[KeyType, ConnType], error) {
 	if len(cfg.Shards) == 0 {
-		return nil, errors.New("at least one shard config is required")
+		return nil, ErrNoShards
 	}
-	if !areShardsUnique(cfg.Shards) {
-		return nil, errors.New("shard configurations are not unique")
+	if err := checkShards(cfg.Shards, cfg.AllowDuplicateAddrs, cfg.RequireContiguousIDs); err != nil {
+		return nil, err
 	}
 	if cfg.Connect == nil {
-		return nil, errors.New("connect func cannot be nil")
+		return nil, ErrNilConnect
+	}
+	for key, id := range cfg.Overrides {
+		if !slices.ContainsFunc(cfg.Shards, func(sc ShardConfig) bool { return sc.ID == id }) {
+			return nil, fmt.Errorf("override of key %q: shard %d not found", key, id)
+		}
+	}
+	// validate all shards before any of them is dialed
+	for _, sc := range cfg.Shards {
+		if err := sc.valid(); err != nil {
+			return nil, err
+		}
+		if cfg.Lazy && len(sc.ReadAddrs) > 0 {
+			return nil, errors.New("read replicas are not supported in lazy mode")
+		}
 	}
 	var (
 		c = &cluster[KeyType, ConnType]{
@@ -36,86 +60,402 @@ func Connect[KeyType ID, ConnType any](cfg Config[KeyType, ConnType]) (Cluster[K
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	c.keyExtractor = cfg.KeyExtractor
+	c.typePrefixed = cfg.TypePrefixed
+	c.sampler = newSampler(cfg.SampleRate)
+	c.ops = newShardOps()
+	c.metrics = cfg.Metrics
+	c.secondaryRouter = cfg.SecondaryRouter
+	c.overrides = maps.Clone(cfg.Overrides)
+	c.shardLess = cfg.ShardLess
+	c.allowDuplicateAddrs = cfg.AllowDuplicateAddrs
+	c.requireContiguousIDs = cfg.RequireContiguousIDs
 	if cfg.Strategy != nil {
-		c.calc = cfg.Strategy
+		c.calc = withOverrides(cfg.Strategy, c.overrides)
 	} else {
-		c.calc = NewDefaultStrategy[KeyType, ConnType](nil)
+		c.calc = withOverrides(c.defaultStrategy(), c.overrides)
+	}
+	c.ping = cfg.PingFunc
+	c.health = cfg.HealthCheck
+	c.onSelect = cfg.OnSelect
+	c.tracer = cfg.Tracer
+	c.cancelOnError = cfg.CancelOnError
+	c.selector = cfg.ReplicaSelector
+	c.breaker = cfg.Breaker
+	c.shardTimeout = cfg.ShardTimeout
+	c.fallback = cfg.Fallback
+	c.skipDownShards = cfg.SkipDownShards
+	c.onTopologyChange = cfg.OnTopologyChange
+	c.logger = cfg.Logger
+	c.dial = cfg.connectShard
+	// bound only the eager connect phase, ctx is used by lazy dials
+	dialCtx := ctx
+	if cfg.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		dialCtx, cancel = context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer cancel()
 	}
 	for _, sc := range cfg.Shards {
-		if err = sc.valid(); err != nil {
-			return nil, err
+		if cfg.Lazy {
+			addr := sc.Addr
+			id := sc.ID
+			scCtx := withShardConfig(ctx, sc)
+			s := newLazyShard(id, addr, func() (ConnType, error) {
+				conn, err := cfg.connect(scCtx, id, addr)
+				if err == nil {
+					cfg.logger().Printf("sharding: lazily connected shard=%d", id)
+				}
+				return conn, err
+			})
+			s.name = sc.name
+			s.meta = copyMeta(sc.Meta)
+			s.weight = sc.Weight
+			s.tls = sc.TLS
+			c.list = append(c.list, s)
+			continue
 		}
 		wg.Add(1)
-		go func(id int64, dsn string) {
+		go func(sc ShardConfig) {
 			defer wg.Done()
-			conn, err := cfg.Connect(ctx, dsn)
+			s, err := cfg.connectShard(dialCtx, sc)
 			if err != nil {
-				errCh <- err
+				errCh <- &ShardError{sc.ID, err}
 				return
 			}
-			s := &shard[ConnType]{id, conn}
+			cfg.logger().Printf("sharding: connected shard=%d", sc.ID)
 			mu.Lock()
 			c.list = append(c.list, s)
 			mu.Unlock()
-		}(sc.ID, sc.Addr)
+		}(sc)
 	}
 	wg.Wait()
 	close(errCh)
-	if err = <-errCh; err != nil {
+	// report all failed shards at once, ordered by id
+	errs := make([]error, 0, len(errCh))
+	for err := range errCh {
+		errs = append(errs, err)
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].(*ShardError).ID < errs[j].(*ShardError).ID
+	})
+	if err = errors.Join(errs...); err != nil {
+		// don't leak connections of the shards which did connect
+		for _, s := range c.list {
+			_ = s.(ownShard).closeConns()
+		}
 		return nil, err
 	}
 	sort.Slice(c.list, func(i, j int) bool {
 		return c.list[i].ID() < c.list[j].ID()
 	})
+	if c.shardLess != nil {
+		sort.SliceStable(c.list, func(i, j int) bool {
+			return c.less(c.list[i], c.list[j])
+		})
+	}
 	return c, nil
 }
 
 // Config struct.
 type Config[KeyType ID, ConnType any] struct {
-	Connect  ConnectFunc[ConnType]       // required. connection func
-	Shards   []ShardConfig               // required. shards config.
-	Context  context.Context             // optional. defaults to context.Background()
-	Strategy Strategy[KeyType, ConnType] // optional. defaults to defaultStrategy.
+	Connect              ConnectFunc[ConnType]               // required. connection func
+	Shards               []ShardConfig                       // required. shards config.
+	Context              context.Context                     // optional. defaults to context.Background()
+	Strategy             Strategy[KeyType, ConnType]         // optional. defaults to defaultStrategy.
+	PingFunc             PingFunc[ConnType]                  // optional. used by Cluster.Ping when ConnType is not a Pinger.
+	HealthCheck          PingFunc[ConnType]                  // optional. used by Cluster.HealthCheckAll.
+	ConnectRetries       int                                 // optional. number of retries of a failed Connect call, defaults to 0.
+	ConnectBackoff       time.Duration                       // optional. delay before the first retry, doubled on each next one.
+	Lazy                 bool                                // optional. defer connecting to a shard until its first use, see Shard.ConnE.
+	OnSelect             func(shardID int64, key any)        // optional. called by Cluster.One and Cluster.OneByHash (with the hash as key) after a shard is resolved.
+	Tracer               Tracer                              // optional. traces EachCtx and ByKeysCtx calls.
+	CancelOnError        bool                                // optional. cancel ctx of other shards in EachCtx and ByKeysCtx when one fails.
+	Logger               Logger                              // optional. logs connection lifecycle events, defaults to no-op.
+	RequireContiguousIDs bool                                // optional. require shard ids to be 1..N without gaps.
+	AfterConnect         func(id int64, conn ConnType) error // optional. called after each connection (incl. read replicas) is established, e.g. to tune pools. An error closes the connection and fails the attempt, which is retried like a failed Connect call.
+	OnConnected          func(id int64, d time.Duration)     // optional. called after each successful Connect call with its duration.
+	ReplicaSelector      ReplicaSelector[ConnType]           // optional. picks a read replica in Cluster.OneRead, defaults to round-robin.
+	Breaker              Breaker                             // optional. skips failing shards in EachCtx and ByKeysCtx, see NewBreaker.
+	ShardTimeout         time.Duration                       // optional. bounds ctx passed to fn for each shard in EachCtx and ByKeysCtx.
+	AllowDuplicateAddrs  bool                                // optional. allow shards sharing an address, e.g. one local database, ids must still be unique.
+	Fallback             bool                                // optional. route keys of shards marked down by Cluster.SetShardDown to the next shard in Cluster.One.
+	SkipDownShards       bool                                // optional. don't call fn for shards marked down in EachCtx and ByKeysCtx.
+	OnTopologyChange     func(change TopologyChange)         // optional. called by Cluster.Watch after shards are changed.
+	KeyExtractor         func(KeyType) KeyType               // optional. reduces a key to its routing part before hashing in the default strategy, e.g. a tenant prefix.
+	SampleRate           float64                             // optional. fraction (0..1] of Cluster.One calls counted in Cluster.SelectionCounts, defaults to 0 (off).
+	TypePrefixed         bool                                // optional. hash keys with NewTypePrefixedHash in the default strategy, so each key type has its own hash space. It changes placement, so choose it when the cluster is created.
+	ConnectTimeout       time.Duration                       // optional. bounds the time Connect waits for all shards to be dialed, incl. retries.
+	Metrics              *Metrics                            // optional. collects per-shard counters, see Metrics.
+	SecondaryRouter      func(attr any) []int64              // optional. returns ids of shards which may hold records matching attr in Cluster.AllFor, unknown ids are ignored.
+	Overrides            map[string]int64                    // optional. pins keys, by their fmt.Sprint form, to shard ids regardless of the strategy, e.g. for legacy data locations.
+	ShardLess            func(a, b ShardConfig) bool         // optional. orders shards in Cluster.All, e.g. by range start for a range strategy, defaults to id ascending. The next shard for Fallback and Replicas follows this order.
+	ValidateConn         PingFunc[ConnType]                  // optional. checks each new connection (incl. read replicas) is usable, e.g. with db.PingContext, as sql.Open doesn't dial. An error fails the connection attempt, which is retried like a failed Connect call.
+}
+
+// dial calls cfg.Connect, but returns as soon as ctx is done even when
+// cfg.Connect ignores ctx. In that case cfg.Connect keeps running in its
+// own goroutine until it returns, and a connection it returns is closed.
+func (cfg *Config[KeyType, ConnType]) dial(ctx context.Context, addr string) (ConnType, error) {
+	if ctx.Done() == nil {
+		return cfg.Connect(ctx, addr)
+	}
+	type result struct {
+		conn ConnType
+		err  error
+	}
+	var (
+		ch        = make(chan result)
+		abandoned = make(chan struct{})
+	)
+	go func() {
+		conn, err := cfg.Connect(ctx, addr)
+		select {
+		case ch <- result{conn, err}:
+		case <-abandoned:
+			if err == nil {
+				_ = closeConn(conn)
+			}
+		}
+	}()
+	select {
+	case r := <-ch:
+		return r.conn, r.err
+	case <-ctx.Done():
+		close(abandoned)
+		var conn ConnType
+		return conn, fmt.Errorf("connect: %w", ctx.Err())
+	}
+}
+
+// connect calls cfg.Connect, retrying up to cfg.ConnectRetries times with
+// exponential backoff. Retries stop early when ctx is done.
+func (cfg *Config[KeyType, ConnType]) connect(ctx context.Context, id int64, addr string) (ConnType, error) {
+	var (
+		conn    ConnType
+		err     error
+		log     = cfg.logger()
+		backoff = cfg.ConnectBackoff
+	)
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		conn, err = cfg.dial(ctx, addr)
+		d := time.Since(start)
+		if err == nil && cfg.ValidateConn != nil {
+			if err = cfg.ValidateConn(ctx, conn); err != nil {
+				_ = closeConn(conn)
+				var zero ConnType
+				conn, err = zero, fmt.Errorf("validate: %w", err)
+			}
+		}
+		if err == nil && cfg.OnConnected != nil {
+			cfg.OnConnected(id, d)
+		}
+		if err == nil && cfg.AfterConnect != nil {
+			if err = cfg.AfterConnect(id, conn); err != nil {
+				_ = closeConn(conn)
+				var zero ConnType
+				conn, err = zero, fmt.Errorf("after connect: %w", err)
+			}
+		}
+		if err == nil {
+			return conn, nil
+		}
+		err = newRedactedError(err, addr)
+		if attempt >= cfg.ConnectRetries {
+			log.Printf("sharding: connect failed shard=%d attempts=%d err=%v", id, attempt+1, err)
+			return conn, err
+		}
+		log.Printf("sharding: connect retry shard=%d attempt=%d backoff=%s err=%v", id, attempt+1, backoff, err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			err = errors.Join(err, ctx.Err())
+			log.Printf("sharding: connect failed shard=%d attempts=%d err=%v", id, attempt+1, err)
+			return conn, err
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+// connectShard connects to the shard primary and its read replicas if any.
+func (cfg *Config[KeyType, ConnType]) connectShard(ctx context.Context, sc ShardConfig) (Shard[ConnType], error) {
+	ctx = withShardConfig(ctx, sc)
+	conn, err := cfg.connect(ctx, sc.ID, sc.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if len(sc.ReadAddrs) == 0 {
+		return &shard[ConnType]{id: sc.ID, conn: conn, addr: sc.Addr, name: sc.name, meta: copyMeta(sc.Meta), weight: sc.Weight, tls: sc.TLS}, nil
+	}
+	reads := make([]ConnType, 0, len(sc.ReadAddrs))
+	for _, addr := range sc.ReadAddrs {
+		r, err := cfg.connect(ctx, sc.ID, addr)
+		if err != nil {
+			// don't leak connections opened so far
+			_ = closeConn(conn)
+			for _, r := range reads {
+				_ = closeConn(r)
+			}
+			return nil, err
+		}
+		reads = append(reads, r)
+	}
+	return &replicatedShard[ConnType]{
+		shard:     shard[ConnType]{id: sc.ID, conn: conn, addr: sc.Addr, name: sc.name, meta: copyMeta(sc.Meta), weight: sc.Weight, tls: sc.TLS},
+		reads:     reads,
+		readAddrs: sc.ReadAddrs,
+	}, nil
+}
+
+func (cfg *Config[KeyType, ConnType]) logger() Logger {
+	if cfg.Logger == nil {
+		return nopLogger{}
+	}
+	return cfg.Logger
+}
+
+// Validation errors returned by Connect.
+var (
+	ErrNoShards        = errors.New("at least one shard config is required")
+	ErrDuplicateShards = errors.New("shard configurations are not unique")
+	ErrNilConnect      = errors.New("connect func cannot be nil")
+)
+
+// ErrInvalidShard is returned by Connect for an invalid shard config.
+type ErrInvalidShard struct {
+	ID     int64
+	Reason string
+}
+
+// Error returns the validation error message.
+func (e *ErrInvalidShard) Error() string {
+	return fmt.Sprintf("validation: invalid shard %d: %s", e.ID, e.Reason)
 }
 
+// ErrNoHealthCheck is returned by Cluster.HealthCheckAll when Config.HealthCheck is not set.
+var ErrNoHealthCheck = errors.New("health check func is not set")
+
+// ErrShardDown is reported by Cluster.ByKeysPartial for shards skipped
+// because they're marked down, see Config.SkipDownShards.
+var ErrShardDown = errors.New("shard is marked down")
+
 // ID type definition.
 type ID interface {
 	string | []byte | int64 | uint64
 }
 
-// ConnectFunc wraps connection func.
+// ConnectFunc wraps connection func. It should respect ctx: Connect stops
+// waiting for it once ctx is done, but an implementation ignoring ctx keeps
+// running (and leaks its goroutine) until it returns. The config of the shard
+// being connected is available via ShardConfigFromContext.
 type ConnectFunc[ConnType any] func(ctx context.Context, addr string) (ConnType, error)
 
+type shardConfigKey struct{}
+
+// ShardConfigFromContext returns the config of the shard being connected.
+// It's set on the ctx passed to ConnectFunc and Config.ValidateConn, for
+// the primary and read replicas alike, e.g. to apply ShardConfig.TLS.
+func ShardConfigFromContext(ctx context.Context) (ShardConfig, bool) {
+	sc, ok := ctx.Value(shardConfigKey{}).(ShardConfig)
+	return sc, ok
+}
+
+func withShardConfig(ctx context.Context, sc ShardConfig) context.Context {
+	return context.WithValue(ctx, shardConfigKey{}, sc)
+}
+
+// Pinger is implemented by connections that can be checked for liveness,
+// e.g. *sql.DB.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingFunc checks a single connection for liveness.
+type PingFunc[ConnType any] func(ctx context.Context, conn ConnType) error
+
 // ShardConfig type include constant connection id and dsn.
 type ShardConfig struct {
-	ID   int64  `json:"id"`
-	Addr string `json:"dsn"`
+	ID        int64             `json:"id"`
+	Addr      string            `json:"dsn"`
+	ReadAddrs []string          `json:"read_dsn,omitempty"` // optional. read replicas, see ReplicatedShard.
+	Meta      map[string]string `json:"meta,omitempty"`     // optional. arbitrary metadata, e.g. region, see Shard.Meta.
+	Weight    int               `json:"weight,omitempty"`   // optional. relative capacity of the shard, 0 means 1, see ClusterStats.TotalWeight.
+	TLS       *tls.Config       `json:"-"`                  // optional. TLS material for the shard, applied by ConnectFunc, see ShardConfigFromContext.
+	name      string            // set by ConnectNamed, see NamedShard.
 }
 
 func (cfg *ShardConfig) valid() error {
-	if cfg.ID == 0 {
-		return errors.New("validation: invalid shard id")
+	if cfg.ID < 1 {
+		return &ErrInvalidShard{cfg.ID, "invalid id for " + redact(cfg.Addr)}
 	}
 	if strings.TrimSpace(cfg.Addr) == "" {
-		return errors.New("validation: invalid dsn")
+		return &ErrInvalidShard{cfg.ID, "invalid dsn"}
+	}
+	for _, addr := range cfg.ReadAddrs {
+		if strings.TrimSpace(addr) == "" {
+			return &ErrInvalidShard{cfg.ID, "invalid read dsn"}
+		}
+	}
+	if cfg.Weight < 0 {
+		return &ErrInvalidShard{cfg.ID, "invalid weight"}
 	}
 	return nil
 }
 
-const shardAddr = "SHARD_ADDRESS"
+// weight returns the shard weight, 1 when it's not set.
+func (cfg ShardConfig) weight() int {
+	return max(cfg.Weight, 1)
+}
+
+const (
+	shardAddr   = "SHARD_ADDRESS"
+	shardWeight = "SHARD_WEIGHT"
+)
 
 // ShardsConfigFromEnv loads parses environment variables and searches for
 // variables called [prefix_]SHARD_ADDRESS_n, where prefix is optional and
-// n is an increment number.
+// n is the shard id. Ids may be sparse, e.g. SHARD_ADDRESS_10,
+// SHARD_ADDRESS_20. When none are found, a single SHARD_ADDRESS variable
+// is loaded as shard 1. The weight of shard n is read from an optional
+// [prefix_]SHARD_WEIGHT_n variable, it's 1 when missing or not a positive
+// integer.
 func ShardsConfigFromEnv(prefix ...string) []ShardConfig {
-	p := ""
+	opts := EnvOptions{}
 	if len(prefix) == 1 {
-		if strings.HasSuffix(prefix[0], "_") {
-			p = prefix[0]
-		} else {
-			p = prefix[0] + "_"
+		opts.Prefix = prefix[0]
+	}
+	return ShardsConfigFromEnvWith(opts)
+}
+
+// EnvOptions configures ShardsConfigFromEnvWith.
+type EnvOptions struct {
+	Prefix     string // optional. variables prefix, "_" separator is added if missing.
+	Contiguous bool   // optional. only load ids 1, 2, 3... stopping at the first gap.
+}
+
+// ShardsConfigFromEnvWith is ShardsConfigFromEnv with options.
+func ShardsConfigFromEnvWith(opts EnvOptions) []ShardConfig {
+	p := opts.Prefix
+	if p != "" && !strings.HasSuffix(p, "_") {
+		p += "_"
+	}
+	var shards []ShardConfig
+	if opts.Contiguous {
+		shards = contiguousShardsFromEnv(p)
+	} else {
+		shards = sparseShardsFromEnv(p)
+	}
+	if len(shards) == 0 {
+		if addr := os.Getenv(fmt.Sprintf("%s%s", p, shardAddr)); addr != "" {
+			shards = append(shards, ShardConfig{ID: 1, Addr: addr, Weight: 1})
 		}
 	}
+	return shards
+}
+
+func contiguousShardsFromEnv(p string) []ShardConfig {
 	var (
 		shards       = make([]ShardConfig, 0)
 		id     int64 = 1
@@ -125,17 +465,81 @@ func ShardsConfigFromEnv(prefix ...string) []ShardConfig {
 		if addr == "" {
 			break
 		}
-		shards = append(shards, ShardConfig{id, addr})
+		shards = append(shards, ShardConfig{ID: id, Addr: addr, Weight: weightFromEnv(p, id)})
 		id++
 	}
-	if len(shards) == 0 {
-		if addr := os.Getenv(fmt.Sprintf("%s%s", p, shardAddr)); addr != "" {
-			shards = append(shards, ShardConfig{1, addr})
+	return shards
+}
+
+func sparseShardsFromEnv(p string) []ShardConfig {
+	var (
+		shards = make([]ShardConfig, 0)
+		re     = regexp.MustCompile("^" + regexp.QuoteMeta(p+shardAddr) + `_(\d+)$`)
+	)
+	for _, env := range os.Environ() {
+		key, addr, _ := strings.Cut(env, "=")
+		m := re.FindStringSubmatch(key)
+		if m == nil || addr == "" {
+			continue
 		}
+		id, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || id < 1 {
+			continue
+		}
+		shards = append(shards, ShardConfig{ID: id, Addr: addr, Weight: weightFromEnv(p, id)})
 	}
+	sort.Slice(shards, func(i, j int) bool {
+		return shards[i].ID < shards[j].ID
+	})
 	return shards
 }
 
+// weightFromEnv returns the weight of shard id, 1 when it's not set.
+func weightFromEnv(p string, id int64) int {
+	w, err := strconv.Atoi(os.Getenv(fmt.Sprintf("%s%s_%d", p, shardWeight, id)))
+	if err != nil || w < 1 {
+		return 1
+	}
+	return w
+}
+
+// ShardsConfigFromJSONEnv reads shard configs from environment variable key
+// containing a JSON array, e.g. [{"id":1,"dsn":"..."}].
+func ShardsConfigFromJSONEnv(key string) ([]ShardConfig, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok || strings.TrimSpace(v) == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", key)
+	}
+	var shards []ShardConfig
+	if err := json.Unmarshal([]byte(v), &shards); err != nil {
+		return nil, fmt.Errorf("environment variable %s is malformed: %w", key, err)
+	}
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("environment variable %s contains no shards", key)
+	}
+	if !areShardsUnique(shards) {
+		return nil, ErrDuplicateShards
+	}
+	return shards, nil
+}
+
+// checkShards validates uniqueness of shard ids and addresses, only of
+// ids when allowDuplicateAddrs is set, and when requireContiguousIDs is set
+// that ids are 1..N.
+func checkShards(shards []ShardConfig, allowDuplicateAddrs, requireContiguousIDs bool) error {
+	if allowDuplicateAddrs {
+		if !areShardIDsUnique(shards) {
+			return fmt.Errorf("%w: duplicate ids", ErrDuplicateShards)
+		}
+	} else if !areShardsUnique(shards) {
+		return ErrDuplicateShards
+	}
+	if requireContiguousIDs && !areShardIDsContiguous(shards) {
+		return errors.New("shard ids must be contiguous starting from 1")
+	}
+	return nil
+}
+
 func areShardsUnique(shards []ShardConfig) bool {
 	ids := make(map[int64]struct{}, len(shards))
 	addresses := make(map[string]struct{}, len(shards))
@@ -148,6 +552,34 @@ func areShardsUnique(shards []ShardConfig) bool {
 			return false
 		}
 		addresses[s.Addr] = struct{}{}
+		for _, addr := range s.ReadAddrs {
+			if _, ex := addresses[addr]; ex {
+				return false
+			}
+			addresses[addr] = struct{}{}
+		}
+	}
+	return true
+}
+
+func areShardIDsUnique(shards []ShardConfig) bool {
+	ids := make(map[int64]struct{}, len(shards))
+	for _, s := range shards {
+		if _, ex := ids[s.ID]; ex {
+			return false
+		}
+		ids[s.ID] = struct{}{}
+	}
+	return true
+}
+
+func areShardIDsContiguous(shards []ShardConfig) bool {
+	seen := make([]bool, len(shards))
+	for _, s := range shards {
+		if s.ID < 1 || s.ID > int64(len(shards)) || seen[s.ID-1] {
+			return false
+		}
+		seen[s.ID-1] = true
 	}
 	return true
 }
@@ -155,162 +587,1797 @@ func areShardsUnique(shards []ShardConfig) bool {
 // Cluster interface.
 type Cluster[KeyType ID, ConnType any] interface {
 
-	// All returns all shards.
+	// All returns all shards in shard order, which is ascending id unless
+	// Config.ShardLess is set.
 	All() []Shard[ConnType]
 
-	// One returns Shard by key.
+	// AllFor returns shards which may hold records matching attr, a
+	// secondary attribute the data isn't sharded by, in shard order. These are
+	// the shards Config.SecondaryRouter returns ids of, e.g. looked up in an
+	// external index, or all shards when it's not set.
+	AllFor(attr any) []Shard[ConnType]
+
+	// Range calls fn for each shard in shard order until fn returns false.
+	// Unlike All, it doesn't copy the shard list. It iterates the shards
+	// as of the call, fn may use or change the cluster.
+	Range(fn func(s Shard[ConnType]) bool)
+
+	// Shards returns an iterator over shards in shard order to be used as
+	// `for s := range c.Shards()`. Like Range, it iterates the shards as of
+	// the start of the loop, its body may use or change the cluster.
+	Shards() iter.Seq[Shard[ConnType]]
+
+	// IDs returns ids of all shards sorted ascending, regardless of
+	// Config.ShardLess.
+	IDs() []int64
+
+	// Len returns the number of shards.
+	Len() int
+
+	// Stats returns a snapshot of the cluster composition.
+	Stats() ClusterStats
+
+	// Topology returns ids and redacted addresses of shards in shard order,
+	// e.g. to be served as JSON by a control-plane API.
+	Topology() []ShardInfo
+
+	// Strategy returns the active Strategy.
+	Strategy() Strategy[KeyType, ConnType]
+
+	// Context returns the context passed to WithContext, or
+	// context.Background() when there's none. Config.Context only bounds
+	// connecting. Each, ByKeys and their variants without a ctx argument
+	// run with it.
+	Context() context.Context
+
+	// WithContext returns a shallow copy of the cluster bound to ctx. The
+	// copy shares the current shards and their connections, nothing is
+	// re-dialed. Shards added or removed afterwards on either cluster are
+	// not reflected in the other one. Lazy shards are shared as well, so
+	// they dial with Config.Context, not with ctx.
+	WithContext(ctx context.Context) Cluster[KeyType, ConnType]
+
+	// Clone returns a copy of the cluster with the same shards, strategy
+	// and options, but its own shard list: adding or removing shards on
+	// either cluster doesn't affect the other one. ConnType values are
+	// shared, not copied, so Reconnect on one cluster closes connections
+	// still used by the other one.
+	Clone() Cluster[KeyType, ConnType]
+
+	// Filter returns a Clone holding only the shards pred returns true
+	// for, e.g. shards of one region. Keys are routed by the same strategy
+	// among these shards only, so One must not be called when none match.
+	Filter(pred func(s Shard[ConnType]) bool) Cluster[KeyType, ConnType]
+
+	// SetStrategy replaces the active Strategy, nil restores the default one.
+	// Changing the strategy changes key placement: keys stored under the
+	// previous strategy may resolve to other shards, so existing data has to
+	// be migrated (or read through both strategies) before switching.
+	SetStrategy(s Strategy[KeyType, ConnType])
+
+	// One returns Shard by key. It panics with ErrNoShards when the cluster
+	// has no shards, e.g. after all of them were removed, see OneOrErr.
 	One(key KeyType) Shard[ConnType]
 
+	// OneOrErr returns Shard by key like One, or ErrNoShards when the
+	// cluster has no shards.
+	OneOrErr(key KeyType) (Shard[ConnType], error)
+
+	// HashOf returns the hash the active Strategy places key by, e.g. to
+	// log why a key landed on its shard; OneByHash(HashOf(key)) is the same
+	// shard as One(key). Returns 0 for strategies which don't hash keys,
+	// like NewFixedStrategy and NewRangeStrategy, or don't implement
+	// KeyHasher.
+	HashOf(key KeyType) uint64
+
+	// SetShardDown marks shard id down, e.g. on an external health signal.
+	// When Config.Fallback is set, One and OneByHash route keys of down
+	// shards to the next shard in shard order which is up. This changes
+	// placement: it's meant to keep serving e.g. cache lookups while a shard
+	// is unavailable, not for data that must be found on its own shard
+	// later. When Config.SkipDownShards is set, EachCtx and ByKeysCtx (and
+	// so Each and ByKeys) don't call fn for down shards.
+	SetShardDown(id int64)
+
+	// SetShardUp marks shard id up again, see SetShardDown.
+	SetShardUp(id int64)
+
+	// IsShardUp reports whether shard id is not marked down.
+	IsShardUp(id int64) bool
+
+	// SelectionCounts returns the cumulative number of sampled Cluster.One
+	// calls by the id of the shard picked, e.g. to detect hot shards. It's
+	// empty unless Config.SampleRate is set. Counts are not scaled by the
+	// rate. Copies of the cluster share the counts.
+	SelectionCounts() map[int64]uint64
+
+	// OneByHash returns Shard by a precomputed hash of key, e.g. a token
+	// hashed upstream, bypassing Hash.Sum. It's the shard One would return
+	// for a key hashing to sum, and it panics with ErrNoShards likewise.
+	OneByHash(sum uint64) Shard[ConnType]
+
+	// Replicas returns n distinct shards for key: its primary shard followed
+	// by the next shards in shard order, wrapping around. When n exceeds the
+	// number of shards, all shards are returned once.
+	Replicas(key KeyType, n int) []Shard[ConnType]
+
+	// ReplicasHRW returns k shards with the highest rendezvous weights for
+	// key hashed with NewDefaultHash, heaviest first. Unlike Replicas, the
+	// set for a key only changes when one of its shards is removed or a
+	// heavier shard is added. When k exceeds the number of shards, all
+	// shards are returned.
+	ReplicasHRW(key KeyType, k int) []Shard[ConnType]
+
+	// OneRead returns a read connection of the shard by key. For shards
+	// with read replicas (see ReplicatedShard) it's one of the replicas
+	// picked by Config.ReplicaSelector, otherwise it's the shard connection.
+	OneRead(key KeyType) ConnType
+
 	// Each runs fn on each shard within cluster.
 	Each(fn func(s Shard[ConnType]) error) error
 
+	// EachSequential runs fn on each shard one by one in shard id order,
+	// stopping at the first error.
+	EachSequential(fn func(s Shard[ConnType]) error) error
+
+	// EachBatched splits shards into consecutive chunks of batchSize in
+	// shard order, the last one holding the remainder, and calls fn with
+	// each chunk one by one, stopping at the first error, e.g. to process
+	// hundreds of shards in waves. fn decides how to run a chunk.
+	EachBatched(batchSize int, fn func([]Shard[ConnType]) error) error
+
+	// Broadcast runs fn on all shards concurrently, e.g. to write data
+	// replicated to every shard, and returns nil when it succeeds on at
+	// least minSuccess shards. Otherwise failures are returned joined, each
+	// as *ShardError.
+	Broadcast(fn func(s Shard[ConnType]) error, minSuccess int) error
+
 	// Map takes a list of identifiers and returns a map[] where the key is the corresponding
-	// shard and the value is a slice of ids that belong to shard.
+	// shard and the value is a slice of ids that belong to shard. It's empty
+	// when the cluster has no shards.
 	Map(ids []KeyType) map[Shard[ConnType]][]KeyType
 
-	// ByKeys executes fn on each result of Map func.
+	// Placement returns shard id to keys mapping like Map does, without
+	// exposing shards, e.g. to inspect placement of keys before writing.
+	Placement(ids []KeyType) map[int64][]KeyType
+
+	// MapSorted is like Map, but returns groups sorted by shard id. Keys
+	// within each group retain their relative input order.
+	MapSorted(ids []KeyType) []ShardKeys[KeyType, ConnType]
+
+	// ByKeys executes fn on each result of Map func. It returns ErrNoShards
+	// for non empty ids when the cluster has no shards, as do ByKeysCtx,
+	// ByKeysStream, ByKeysIndexed and ByKeysRetry.
 	ByKeys(ids []KeyType, fn func([]KeyType, Shard[ConnType]) error) error
+
+	// ByKeysStream routes ids to per-shard buffers and calls fn with a
+	// buffer as soon as it holds batchSize ids, then with the remainders in
+	// shard order, so memory is bounded regardless of the number of ids.
+	// fn is called sequentially and must not retain the ids slice. The
+	// first error stops the iteration and is returned.
+	ByKeysStream(ids iter.Seq[KeyType], fn func([]KeyType, Shard[ConnType]) error, batchSize int) error
+
+	// ByKeysPartial executes fn on each result of Map func like ByKeys, but
+	// returns errors keyed by id of the shard they occurred on, so only
+	// failed shards can be retried. Shards which weren't called, e.g. with
+	// an open breaker or marked down (ErrShardDown), are reported too. An
+	// empty map means total success. Non empty ids on a cluster without
+	// shards are reported as ErrNoShards under id 0, which no shard has.
+	ByKeysPartial(ids []KeyType, fn func([]KeyType, Shard[ConnType]) error) map[int64]error
+
+	// ByKeysIndexed executes fn on ids grouped by shard like ByKeys, passing
+	// the positions of the ids in the input alongside them, so results can
+	// be placed into an output slice in input order.
+	ByKeysIndexed(ids []KeyType, fn func(idxs []int, ids []KeyType, s Shard[ConnType]) error) error
+
+	// ByKeysRetry executes fn on each result of Map func like ByKeys, and
+	// retries it for the shards it failed on, up to attempts calls per
+	// shard in total, waiting backoff before the first retry and doubling
+	// it on each next one. It's meant for idempotent writes. Each attempt
+	// is run like in ByKeysCtx, so a panic in fn is a failed attempt and
+	// Config.Breaker, ShardTimeout and SkipDownShards apply. Errors of
+	// shards still failing are returned joined, each as *ShardError with
+	// the last error.
+	ByKeysRetry(ids []KeyType, fn func([]KeyType, Shard[ConnType]) error, attempts int, backoff time.Duration) error
+
+	// EachCtx runs fn on each shard within cluster passing ctx to it.
+	// fn is not called for shards when ctx is already done, ctx.Err() is
+	// returned for them instead. When Config.CancelOnError is set, ctx
+	// passed to fn is canceled as soon as any shard fails. When
+	// Config.Tracer is set, a span is started for the call and a child
+	// span for each shard. When Config.ShardTimeout is set, ctx passed to
+	// fn expires after it, so fn respecting ctx fails with
+	// context.DeadlineExceeded for a slow shard only.
+	EachCtx(ctx context.Context, fn func(ctx context.Context, s Shard[ConnType]) error) error
+
+	// ByKeysCtx executes fn on each result of Map func passing ctx to it.
+	// Context handling and tracing are the same as in EachCtx.
+	ByKeysCtx(ctx context.Context, ids []KeyType, fn func(ctx context.Context, ids []KeyType, s Shard[ConnType]) error) error
+
+	// AddShard adds a shard to the cluster.
+	AddShard(s Shard[ConnType]) error
+
+	// RemoveShard removes a shard from the cluster by id.
+	RemoveShard(id int64) error
+
+	// RemoveShardsWhere removes all shards matching pred at once, e.g. a
+	// whole region being decommissioned, and returns them in shard order,
+	// so their connections can be closed. The remaining shards keep their
+	// order. pred is called with the cluster locked, so it must not call
+	// the cluster.
+	RemoveShardsWhere(pred func(Shard[ConnType]) bool) []Shard[ConnType]
+
+	// Reconnect re-establishes connections of a shard created by Connect
+	// using its stored address. The shard is replaced with a new one holding
+	// the new connections, then the old connections implementing io.Closer
+	// are closed. On failure, the old shard is kept.
+	Reconnect(ctx context.Context, id int64) error
+
+	// Watch loads shard configs from source right away and then every
+	// interval until ctx is done, and applies changes: new shards are
+	// connected and added, missing ones are removed, shards with a changed
+	// address are reconnected. Weight, metadata and TLS changes are applied
+	// in place, a nil ShardConfig.TLS keeps the current one. Loaded configs
+	// are validated like Config.Shards, honoring Config.AllowDuplicateAddrs
+	// and Config.RequireContiguousIDs. Connections of removed and replaced
+	// shards implementing io.Closer are closed. Config.OnTopologyChange is
+	// called after each change, failures are logged with Config.Logger and
+	// retried on the next poll. Watch blocks until ctx is done and returns
+	// ctx.Err(), it's only supported by clusters created by Connect and a
+	// positive interval.
+	Watch(ctx context.Context, source ConfigSource, interval time.Duration) error
+
+	// DrainShard stops routing keys to shard id in One, OneByHash, Map and
+	// the calls built on it (ByKeys, GroupBy, Pipeline...), they go to the
+	// next shard in shard order instead. Each, EachSequential, EachBatched,
+	// Broadcast, Scatter, Reduce and MergeSorted skip the shard. DrainShard
+	// blocks until fn calls running on it in EachCtx and ByKeysCtx (and so
+	// Each and ByKeys) return or ctx is done. Calls routed to the shard
+	// before it started draining, but not running yet, fail with
+	// ErrShardDraining. The shard keeps draining until removed with
+	// RemoveShard, which is meant to follow once DrainShard returns nil.
+	DrainShard(ctx context.Context, id int64) error
+
+	// InFlight returns the number of fn calls running on shard id in
+	// EachCtx and ByKeysCtx (and so Each and ByKeys). Shards returned by
+	// One are not tracked, as the cluster doesn't see when their use ends.
+	InFlight(id int64) int64
+
+	// Ping checks all shards in parallel. Connections implementing Pinger
+	// (e.g. *sql.DB) are pinged with PingContext, other connections are
+	// checked with Config.PingFunc. Failures are joined and annotated with
+	// shard id.
+	Ping(ctx context.Context) error
+
+	// HealthCheckAll runs Config.HealthCheck on all shards in parallel and
+	// returns joined per-shard errors. Returns ErrNoHealthCheck when
+	// Config.HealthCheck is not set.
+	HealthCheckAll(ctx context.Context) error
+
+	// Warmup establishes the connections of all shards in parallel, which
+	// dials lazy shards (see Config.Lazy), and runs Config.HealthCheck on
+	// them when it's set. It returns joined per-shard errors, so nil means
+	// every shard is live, e.g. before serving traffic. Lazy shards are
+	// dialed with the context of the cluster, ctx bounds the health checks.
+	Warmup(ctx context.Context) error
 }
 
 type cluster[KeyType ID, ConnType any] struct {
-	list []Shard[ConnType]
-	calc Strategy[KeyType, ConnType]
+	mu               sync.RWMutex
+	list             []Shard[ConnType]
+	calc             Strategy[KeyType, ConnType]
+	ping             PingFunc[ConnType]
+	health           PingFunc[ConnType]
+	onSelect         func(shardID int64, key any)
+	tracer           Tracer
+	breaker          Breaker
+	shardTimeout     time.Duration
+	cancelOnError    bool
+	selector         ReplicaSelector[ConnType]
+	dial             func(ctx context.Context, sc ShardConfig) (Shard[ConnType], error)
+	ctx              context.Context
+	fallback         bool
+	skipDownShards   bool
+	down             map[int64]bool
+	onTopologyChange func(change TopologyChange)
+	logger           Logger
+	keyExtractor     func(KeyType) KeyType
+	typePrefixed     bool
+	sampler          *sampler
+	draining         map[int64]bool
+	ops              *shardOps
+	metrics          *Metrics
+	secondaryRouter  func(attr any) []int64
+	overrides        map[string]int64
+	shardLess        func(a, b ShardConfig) bool
+
+	allowDuplicateAddrs  bool
+	requireContiguousIDs bool
 }
 
 // All returns all shards.
 func (c *cluster[KeyType, ConnType]) All() []Shard[ConnType] {
-	return c.list
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshot()
 }
 
-// One returns Shard by key.
-func (c *cluster[KeyType, ConnType]) One(key KeyType) Shard[ConnType] {
-	return c.calc.Find(key, c.list)
+// AllFor returns shards which may hold records matching attr.
+func (c *cluster[KeyType, ConnType]) AllFor(attr any) []Shard[ConnType] {
+	if c.secondaryRouter == nil {
+		return c.All()
+	}
+	ids := make(map[int64]bool)
+	for _, id := range c.secondaryRouter(attr) {
+		ids[id] = true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	list := make([]Shard[ConnType], 0, len(ids))
+	for _, s := range c.list {
+		if ids[s.ID()] {
+			list = append(list, s)
+		}
+	}
+	return list
 }
 
-// Each runs fn on each shard within cluster.
-func (c *cluster[KeyType, ConnType]) Each(fn func(s Shard[ConnType]) error) error {
-	errCh := make(chan error, len(c.list))
-	wg := sync.WaitGroup{}
-	for _, s := range c.list {
-		wg.Add(1)
-		go func(s Shard[ConnType]) {
-			defer wg.Done()
-			if err := fn(s); err != nil {
-				errCh <- err
-			}
-		}(s)
+// IDs returns sorted ids of all shards.
+func (c *cluster[KeyType, ConnType]) IDs() []int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	ids := make([]int64, len(c.list))
+	for i, s := range c.list {
+		ids[i] = s.ID()
 	}
-	wg.Wait()
-	close(errCh)
-	return <-errCh
+	slices.Sort(ids)
+	return ids
 }
 
-// Map takes a list of identifiers and returns a map[] where the key is the corresponding
-// shard and the value is a slice of ids that belong to shard.
-func (c *cluster[KeyType, ConnType]) Map(ids []KeyType) map[Shard[ConnType]][]KeyType {
-	res := make(map[Shard[ConnType]][]KeyType, len(ids))
-	for _, id := range ids {
-		s := c.One(id)
-		if _, ok := res[s]; !ok {
-			res[s] = make([]KeyType, 0, len(ids))
+// Range calls fn for each shard until fn returns false.
+func (c *cluster[KeyType, ConnType]) Range(fn func(s Shard[ConnType]) bool) {
+	// mutations replace c.list, so iterating it unlocked is safe
+	c.mu.RLock()
+	list := c.list
+	c.mu.RUnlock()
+	for _, s := range list {
+		if !fn(s) {
+			return
 		}
-		res[s] = append(res[s], id)
 	}
-	return res
 }
 
-// ByKeys executes fn on each result of Map func.
-func (c *cluster[KeyType, ConnType]) ByKeys(ids []KeyType, fn func([]KeyType, Shard[ConnType]) error) error {
-	m := c.Map(ids)
-	wg := sync.WaitGroup{}
-	errCh := make(chan error, len(m))
-	for s, i := range m {
-		wg.Add(1)
-		go func(ids []KeyType, sh Shard[ConnType]) {
-			defer wg.Done()
-			if err := fn(ids, sh); err != nil {
-				errCh <- err
-			}
-		}(i, s)
-	}
-	wg.Wait()
-	close(errCh)
-	return <-errCh
+// Shards returns an iterator over shards.
+func (c *cluster[KeyType, ConnType]) Shards() iter.Seq[Shard[ConnType]] {
+	return c.Range
 }
 
-// Shard interface.
-type Shard[ConnType any] interface {
-	ID() int64
-	Conn() ConnType
+// Len returns the number of shards.
+func (c *cluster[KeyType, ConnType]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.list)
 }
 
-type shard[ConnType any] struct {
-	id   int64
-	conn ConnType
+// Stats returns a snapshot of the cluster composition.
+func (c *cluster[KeyType, ConnType]) Stats() ClusterStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	st := ClusterStats{
+		ShardCount: len(c.list),
+		ShardIDs:   make([]int64, len(c.list)),
+	}
+	for i, s := range c.list {
+		st.ShardIDs[i] = s.ID()
+		st.TotalWeight += configOf(s).weight()
+	}
+	return st
 }
 
-// ID returns ConnIDType.
-func (s *shard[ConnType]) ID() int64 {
-	return s.id
+// Topology returns ids and redacted addresses of shards.
+func (c *cluster[KeyType, ConnType]) Topology() []ShardInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	res := make([]ShardInfo, len(c.list))
+	for i, s := range c.list {
+		res[i] = ShardInfo{ID: s.ID(), Addr: s.Addr()}
+	}
+	return res
 }
 
-// Conn returns database connection.
-func (s *shard[ConnType]) Conn() ConnType {
-	return s.conn
+// Strategy returns the active Strategy.
+func (c *cluster[KeyType, ConnType]) Strategy() Strategy[KeyType, ConnType] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.calc
 }
 
-// Hash interface.
-type Hash[KeyType ID] interface {
-	Sum(key KeyType) uint64
+// Context returns the context passed to WithContext.
+func (c *cluster[KeyType, ConnType]) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
 }
 
-func NewDefaultHash[KeyType ID]() Hash[KeyType] {
-	return &defaultHash[KeyType]{crc64.MakeTable(crc64.ISO)}
+// WithContext returns a shallow copy of the cluster bound to ctx.
+func (c *cluster[KeyType, ConnType]) WithContext(ctx context.Context) Cluster[KeyType, ConnType] {
+	if ctx == nil {
+		panic("sharding: nil context")
+	}
+	cp := c.copy()
+	cp.ctx = ctx
+	return cp
 }
 
-type defaultHash[KeyType ID] struct {
-	t *crc64.Table
+// Clone returns a copy of the cluster with its own shard list.
+func (c *cluster[KeyType, ConnType]) Clone() Cluster[KeyType, ConnType] {
+	cp := c.copy()
+	cp.list = append([]Shard[ConnType](nil), cp.list...)
+	return cp
 }
 
-// Sum of id.
-func (h *defaultHash[KeyType]) Sum(id KeyType) uint64 {
-	var idb []byte
-	switch i := any(id).(type) {
-	case int64:
-		idb = strconv.AppendInt(idb, i, 10)
-	case uint64:
-		idb = strconv.AppendUint(idb, i, 10)
-	case string:
-		idb = []byte(i)
-	case []byte:
-		idb = i
+// Filter returns a copy of the cluster holding only shards matching pred.
+func (c *cluster[KeyType, ConnType]) Filter(pred func(s Shard[ConnType]) bool) Cluster[KeyType, ConnType] {
+	cp := c.copy()
+	list := make([]Shard[ConnType], 0, len(cp.list))
+	for _, s := range cp.list {
+		if pred(s) {
+			list = append(list, s)
+		}
 	}
-	return crc64.Checksum(idb, h.t)
+	cp.list = list
+	return cp
+}
+
+// copy returns a shallow copy of the cluster sharing its shard list.
+func (c *cluster[KeyType, ConnType]) copy() *cluster[KeyType, ConnType] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return &cluster[KeyType, ConnType]{
+		list:             c.list,
+		calc:             c.calc,
+		ping:             c.ping,
+		health:           c.health,
+		onSelect:         c.onSelect,
+		tracer:           c.tracer,
+		breaker:          c.breaker,
+		shardTimeout:     c.shardTimeout,
+		cancelOnError:    c.cancelOnError,
+		selector:         c.selector,
+		dial:             c.dial,
+		ctx:              c.ctx,
+		fallback:         c.fallback,
+		skipDownShards:   c.skipDownShards,
+		onTopologyChange: c.onTopologyChange,
+		logger:           c.logger,
+		keyExtractor:     c.keyExtractor,
+		typePrefixed:     c.typePrefixed,
+		sampler:          c.sampler,
+		ops:              c.ops,
+		metrics:          c.metrics,
+		secondaryRouter:  c.secondaryRouter,
+		overrides:        c.overrides,
+		shardLess:        c.shardLess,
+
+		allowDuplicateAddrs:  c.allowDuplicateAddrs,
+		requireContiguousIDs: c.requireContiguousIDs,
+		down:                 copyDown(c.down),
+		draining:             copyDown(c.draining),
+	}
+}
+
+// SetStrategy replaces the active Strategy, nil restores the default one.
+func (c *cluster[KeyType, ConnType]) SetStrategy(s Strategy[KeyType, ConnType]) {
+	c.mu.Lock()
+	if s == nil {
+		s = c.defaultStrategy()
+	}
+	c.calc = withOverrides(s, c.overrides)
+	c.mu.Unlock()
+}
+
+// defaultStrategy returns the default Strategy applying Config.KeyExtractor
+// and Config.TypePrefixed.
+func (c *cluster[KeyType, ConnType]) defaultStrategy() Strategy[KeyType, ConnType] {
+	hash := NewDefaultHash[KeyType]()
+	if c.typePrefixed {
+		hash = NewTypePrefixedHash[KeyType]()
+	}
+	return &defaultStrategy[KeyType, ConnType]{
+		hash:    hash,
+		extract: c.keyExtractor,
+	}
+}
+
+// One returns Shard by key.
+func (c *cluster[KeyType, ConnType]) One(key KeyType) Shard[ConnType] {
+	s, err := c.OneOrErr(key)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// HashOf returns the hash the active Strategy places key by.
+func (c *cluster[KeyType, ConnType]) HashOf(key KeyType) uint64 {
+	if h, ok := c.Strategy().(KeyHasher[KeyType]); ok {
+		return h.HashOf(key)
+	}
+	return 0
+}
+
+// OneOrErr returns Shard by key or ErrNoShards.
+func (c *cluster[KeyType, ConnType]) OneOrErr(key KeyType) (Shard[ConnType], error) {
+	// a deferred unlock keeps the lock consistent when Find panics,
+	// e.g. the fixed strategy on an unknown key
+	s := func() Shard[ConnType] {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		if len(c.list) == 0 {
+			return nil
+		}
+		return c.skipDown(c.calc.Find(key, c.list))
+	}()
+	if s == nil {
+		return nil, ErrNoShards
+	}
+	c.sampler.record(s.ID())
+	c.metrics.selected(s.ID())
+	if c.onSelect != nil {
+		c.onSelect(s.ID(), key)
+	}
+	return s, nil
+}
+
+// OneByHash returns Shard by a precomputed hash of key.
+func (c *cluster[KeyType, ConnType]) OneByHash(sum uint64) Shard[ConnType] {
+	s := func() Shard[ConnType] {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		if len(c.list) == 0 {
+			return nil
+		}
+		return c.skipDown(c.calc.FindByHash(sum, c.list))
+	}()
+	if s == nil {
+		panic(ErrNoShards)
+	}
+	c.metrics.selected(s.ID())
+	if c.onSelect != nil {
+		c.onSelect(s.ID(), sum)
+	}
+	return s
+}
+
+// SetShardDown marks shard id down.
+func (c *cluster[KeyType, ConnType]) SetShardDown(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.down == nil {
+		c.down = make(map[int64]bool)
+	}
+	c.down[id] = true
+}
+
+// SetShardUp marks shard id up.
+func (c *cluster[KeyType, ConnType]) SetShardUp(id int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.down, id)
+}
+
+// IsShardUp reports whether shard id is not marked down.
+func (c *cluster[KeyType, ConnType]) IsShardUp(id int64) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.down[id]
+}
+
+// SelectionCounts returns the sampled number of Cluster.One picks by shard id.
+func (c *cluster[KeyType, ConnType]) SelectionCounts() map[int64]uint64 {
+	return c.sampler.snapshot()
+}
+
+// skipDown returns s, or the next shard in shard order which is not excluded
+// when s is draining, or down and fallback is enabled. Caller must hold the
+// lock.
+func (c *cluster[KeyType, ConnType]) skipDown(s Shard[ConnType]) Shard[ConnType] {
+	return nextShard(c.list, s, func(id int64) bool {
+		return c.draining[id] || c.fallback && c.down[id]
+	})
+}
+
+// skipDraining returns s, or the next shard in shard order which is not
+// draining. Caller must hold the lock.
+func (c *cluster[KeyType, ConnType]) skipDraining(s Shard[ConnType]) Shard[ConnType] {
+	if len(c.draining) == 0 {
+		return s
+	}
+	return nextShard(c.list, s, func(id int64) bool {
+		return c.draining[id]
+	})
+}
+
+// nextShard returns s, or the shard following it in list which is not
+// excluded when s is. s is returned when all shards are excluded.
+func nextShard[ConnType any](list []Shard[ConnType], s Shard[ConnType], excluded func(id int64) bool) Shard[ConnType] {
+	if s == nil || !excluded(s.ID()) {
+		return s
+	}
+	start := -1
+	for i := range list {
+		if list[i] == s {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return s
+	}
+	for i := 1; i < len(list); i++ {
+		if next := list[(start+i)%len(list)]; !excluded(next.ID()) {
+			return next
+		}
+	}
+	return s
+}
+
+// active returns the shards which are not draining, see DrainShard.
+func (c *cluster[KeyType, ConnType]) active() []Shard[ConnType] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.draining) == 0 {
+		return c.snapshot()
+	}
+	list := make([]Shard[ConnType], 0, len(c.list))
+	for _, s := range c.list {
+		if !c.draining[s.ID()] {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// copyDown returns a copy of down.
+func copyDown(down map[int64]bool) map[int64]bool {
+	if len(down) == 0 {
+		return nil
+	}
+	res := make(map[int64]bool, len(down))
+	for id := range down {
+		res[id] = true
+	}
+	return res
+}
+
+// Replicas returns n distinct shards for key: its primary shard followed
+// by the next shards in shard order, wrapping around.
+func (c *cluster[KeyType, ConnType]) Replicas(key KeyType, n int) []Shard[ConnType] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if n > len(c.list) {
+		n = len(c.list)
+	}
+	if n < 1 {
+		return nil
+	}
+	primary, start := c.calc.Find(key, c.list), 0
+	for i, s := range c.list {
+		if s == primary {
+			start = i
+			break
+		}
+	}
+	res := make([]Shard[ConnType], 0, n)
+	for i := 0; i < n; i++ {
+		res = append(res, c.list[(start+i)%len(c.list)])
+	}
+	return res
+}
+
+// ReplicasHRW returns k shards with the highest rendezvous weights for key.
+func (c *cluster[KeyType, ConnType]) ReplicasHRW(key KeyType, k int) []Shard[ConnType] {
+	sum := NewDefaultHash[KeyType]().Sum(key)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return hrwTop(sum, c.list, k)
+}
+
+// OneRead returns a read connection of the shard by key.
+func (c *cluster[KeyType, ConnType]) OneRead(key KeyType) ConnType {
+	s := c.One(key)
+	if c.selector != nil {
+		switch r := s.(type) {
+		case *replicatedShard[ConnType]:
+			return c.selector.Pick(r.reads)
+		case ReplicatedShard[ConnType]:
+			return c.selector.Pick(r.ReadConns())
+		}
+	}
+	if r, ok := s.(ReplicatedShard[ConnType]); ok {
+		return r.ReadConn()
+	}
+	return s.Conn()
+}
+
+// Each runs fn on each shard within cluster.
+func (c *cluster[KeyType, ConnType]) Each(fn func(s Shard[ConnType]) error) error {
+	return c.EachCtx(c.Context(), func(_ context.Context, s Shard[ConnType]) error {
+		return fn(s)
+	})
+}
+
+// EachSequential runs fn on each shard one by one in shard id order,
+// stopping at the first error.
+func (c *cluster[KeyType, ConnType]) EachSequential(fn func(s Shard[ConnType]) error) error {
+	list := c.active()
+	// shard order may differ from id order with Config.ShardLess
+	slices.SortFunc(list, func(a, b Shard[ConnType]) int {
+		return cmp.Compare(a.ID(), b.ID())
+	})
+	for _, s := range list {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachBatched calls fn with consecutive chunks of batchSize shards.
+func (c *cluster[KeyType, ConnType]) EachBatched(batchSize int, fn func([]Shard[ConnType]) error) error {
+	if batchSize < 1 {
+		return errors.New("batch size must be positive")
+	}
+	for chunk := range slices.Chunk(c.active(), batchSize) {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Broadcast runs fn on all shards requiring minSuccess of them to succeed.
+func (c *cluster[KeyType, ConnType]) Broadcast(fn func(s Shard[ConnType]) error, minSuccess int) error {
+	var (
+		list = c.active()
+		ok   atomic.Int64
+	)
+	err := eachJoin(list, func(s Shard[ConnType]) error {
+		if err := fn(s); err != nil {
+			return err
+		}
+		ok.Add(1)
+		return nil
+	})
+	if n := int(ok.Load()); n < minSuccess {
+		return errors.Join(fmt.Errorf("broadcast succeeded on %d of %d shards, want %d", n, len(list), minSuccess), err)
+	}
+	return nil
+}
+
+// EachCtx runs fn on each shard within cluster passing ctx to it.
+func (c *cluster[KeyType, ConnType]) EachCtx(
+	ctx context.Context,
+	fn func(ctx context.Context, s Shard[ConnType]) error,
+) (err error) {
+	ctx, finish := c.startSpan(ctx, "sharding.Each")
+	defer func() { finish(err) }()
+	ctx, cancel := c.withCancel(ctx)
+	defer cancel()
+	list := c.active()
+	errCh := make(chan error, len(list))
+	wg := sync.WaitGroup{}
+	for _, s := range list {
+		wg.Add(1)
+		go func(s Shard[ConnType]) {
+			defer wg.Done()
+			if err := c.runShard(ctx, s, func(ctx context.Context) error {
+				return fn(ctx, s)
+			}); err != nil {
+				errCh <- err
+				cancel()
+			}
+		}(s)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+// place returns the shard of each of keys or ErrNoShards, see placer.
+func (c *cluster[KeyType, ConnType]) place(keys []KeyType) ([]Shard[ConnType], error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	res, err := placeOn(c.calc, c.list, keys)
+	for i := range res {
+		res[i] = c.skipDraining(res[i])
+	}
+	return res, err
+}
+
+// Map takes a list of identifiers and returns a map[] where the key is the corresponding
+// shard and the value is a slice of ids that belong to shard.
+func (c *cluster[KeyType, ConnType]) Map(ids []KeyType) map[Shard[ConnType]][]KeyType {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	res := make(map[Shard[ConnType]][]KeyType, len(ids))
+	if len(c.list) == 0 {
+		return res
+	}
+	for _, id := range ids {
+		s := c.skipDraining(c.calc.Find(id, c.list))
+		if _, ok := res[s]; !ok {
+			res[s] = make([]KeyType, 0, len(ids))
+		}
+		res[s] = append(res[s], id)
+	}
+	return res
+}
+
+// ByKeysStream executes fn on batches of ids routed to the same shard.
+func (c *cluster[KeyType, ConnType]) ByKeysStream(
+	ids iter.Seq[KeyType],
+	fn func([]KeyType, Shard[ConnType]) error,
+	batchSize int,
+) error {
+	if batchSize < 1 {
+		return errors.New("batch size must be positive")
+	}
+	c.mu.RLock()
+	list, calc, draining := c.list, c.calc, copyDown(c.draining)
+	c.mu.RUnlock()
+	var (
+		index = make(map[Shard[ConnType]]int, len(list))
+		bufs  = make([][]KeyType, len(list))
+	)
+	for i, s := range list {
+		index[s] = i
+	}
+	flush := func(i int) error {
+		s := list[i]
+		err := safeCall(s.ID(), func() error { return fn(bufs[i], s) })
+		bufs[i] = bufs[i][:0]
+		return shardError(s.ID(), err)
+	}
+	for id := range ids {
+		s := nextShard(list, calc.Find(id, list), func(id int64) bool {
+			return draining[id]
+		})
+		if s == nil {
+			return ErrNoShards
+		}
+		i, ok := index[s]
+		if !ok {
+			return fmt.Errorf("strategy returned shard %d which is not in the cluster", s.ID())
+		}
+		if bufs[i] == nil {
+			bufs[i] = make([]KeyType, 0, batchSize)
+		}
+		bufs[i] = append(bufs[i], id)
+		if len(bufs[i]) == batchSize {
+			if err := flush(i); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range bufs {
+		if len(bufs[i]) > 0 {
+			if err := flush(i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Placement returns shard id to keys mapping like Map does, without
+// exposing shards.
+func (c *cluster[KeyType, ConnType]) Placement(ids []KeyType) map[int64][]KeyType {
+	m := c.Map(ids)
+	res := make(map[int64][]KeyType, len(m))
+	for s, keys := range m {
+		res[s.ID()] = keys
+	}
+	return res
+}
+
+// MapSorted is like Map, but returns groups sorted by shard id. Keys
+// within each group retain their relative input order.
+func (c *cluster[KeyType, ConnType]) MapSorted(ids []KeyType) []ShardKeys[KeyType, ConnType] {
+	m := c.Map(ids)
+	res := make([]ShardKeys[KeyType, ConnType], 0, len(m))
+	for s, keys := range m {
+		res = append(res, ShardKeys[KeyType, ConnType]{s, keys})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Shard.ID() < res[j].Shard.ID()
+	})
+	return res
+}
+
+// ShardKeys is a group of keys that belong to Shard.
+type ShardKeys[KeyType ID, ConnType any] struct {
+	Shard Shard[ConnType]
+	Keys  []KeyType
+}
+
+// ClusterStats is a snapshot of the cluster composition returned by
+// Cluster.Stats.
+type ClusterStats struct {
+	ShardCount  int     `json:"shard_count"`
+	ShardIDs    []int64 `json:"shard_ids"`
+	TotalWeight int     `json:"total_weight"` // sum of shard weights, see ShardConfig.Weight.
+}
+
+// ShardInfo describes a shard in Cluster.Topology.
+type ShardInfo struct {
+	ID   int64  `json:"id"`
+	Addr string `json:"dsn"` // credentials are masked, see Shard.Addr.
+}
+
+// withCancel derives a cancelable ctx when CancelOnError is set. The
+// returned cancel func is always safe to call.
+func (c *cluster[KeyType, ConnType]) withCancel(ctx context.Context) (context.Context, context.CancelFunc) {
+	if !c.cancelOnError {
+		return ctx, func() {}
+	}
+	return context.WithCancel(ctx)
+}
+
+// ByKeys executes fn on each result of Map func.
+func (c *cluster[KeyType, ConnType]) ByKeys(ids []KeyType, fn func([]KeyType, Shard[ConnType]) error) error {
+	return c.ByKeysCtx(c.Context(), ids, func(_ context.Context, ids []KeyType, s Shard[ConnType]) error {
+		return fn(ids, s)
+	})
+}
+
+// ByKeysPartial executes fn on each result of Map func like ByKeys, but
+// returns errors keyed by id of the shard they occurred on. A failed shard
+// doesn't cancel the others.
+func (c *cluster[KeyType, ConnType]) ByKeysPartial(
+	ids []KeyType,
+	fn func([]KeyType, Shard[ConnType]) error,
+) map[int64]error {
+	var (
+		ctx    = c.Context()
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed = make(map[int64]error)
+		m      = c.Map(ids)
+	)
+	if len(m) == 0 && len(ids) > 0 {
+		failed[0] = ErrNoShards
+	}
+	for s, ids := range m {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			called := false
+			err := c.runShard(ctx, s, func(context.Context) error {
+				called = true
+				return fn(ids, s)
+			})
+			if err == nil && !called {
+				err = ErrShardDown
+			}
+			if se := (*ShardError)(nil); errors.As(err, &se) {
+				err = se.Err
+			}
+			if err != nil {
+				mu.Lock()
+				failed[s.ID()] = err
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return failed
+}
+
+// ByKeysIndexed executes fn on ids grouped by shard with their positions.
+func (c *cluster[KeyType, ConnType]) ByKeysIndexed(
+	ids []KeyType,
+	fn func(idxs []int, ids []KeyType, s Shard[ConnType]) error,
+) (err error) {
+	ctx, finish := c.startSpan(c.Context(), "sharding.ByKeys")
+	defer func() { finish(err) }()
+	ctx, cancel := c.withCancel(ctx)
+	defer cancel()
+	type group struct {
+		idxs []int
+		ids  []KeyType
+	}
+	m := make(map[Shard[ConnType]]*group)
+	if err = func() error {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		for i, id := range ids {
+			s := c.skipDraining(c.calc.Find(id, c.list))
+			if s == nil {
+				return ErrNoShards
+			}
+			g, ok := m[s]
+			if !ok {
+				g = &group{}
+				m[s] = g
+			}
+			g.idxs = append(g.idxs, i)
+			g.ids = append(g.ids, id)
+		}
+		return nil
+	}(); err != nil {
+		return err
+	}
+	wg := sync.WaitGroup{}
+	errCh := make(chan error, len(m))
+	for s, g := range m {
+		wg.Add(1)
+		go func(g *group, sh Shard[ConnType]) {
+			defer wg.Done()
+			if err := c.runShard(ctx, sh, func(context.Context) error {
+				return fn(g.idxs, g.ids, sh)
+			}); err != nil {
+				errCh <- err
+				cancel()
+			}
+		}(g, s)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+// ByKeysRetry executes fn on each result of Map func retrying failed shards.
+func (c *cluster[KeyType, ConnType]) ByKeysRetry(
+	ids []KeyType,
+	fn func([]KeyType, Shard[ConnType]) error,
+	attempts int,
+	backoff time.Duration,
+) (err error) {
+	ctx, finish := c.startSpan(c.Context(), "sharding.ByKeys")
+	defer func() { finish(err) }()
+	var (
+		m    = c.Map(ids)
+		list = make([]Shard[ConnType], 0, len(m))
+	)
+	if len(m) == 0 && len(ids) > 0 {
+		return ErrNoShards
+	}
+	for s := range m {
+		list = append(list, s)
+	}
+	return eachJoin(list, func(s Shard[ConnType]) error {
+		delay := backoff
+		for attempt := 1; ; attempt++ {
+			err := c.runShard(ctx, s, func(context.Context) error {
+				return fn(m[s], s)
+			})
+			// eachJoin adds the shard id
+			if se := (*ShardError)(nil); errors.As(err, &se) {
+				err = se.Err
+			}
+			if err == nil || attempt >= attempts {
+				return err
+			}
+			t := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return errors.Join(err, ctx.Err())
+			case <-t.C:
+			}
+			delay *= 2
+		}
+	})
+}
+
+// ByKeysCtx executes fn on each result of Map func passing ctx to it.
+func (c *cluster[KeyType, ConnType]) ByKeysCtx(
+	ctx context.Context,
+	ids []KeyType,
+	fn func(ctx context.Context, ids []KeyType, s Shard[ConnType]) error,
+) (err error) {
+	ctx, finish := c.startSpan(ctx, "sharding.ByKeys")
+	defer func() { finish(err) }()
+	ctx, cancel := c.withCancel(ctx)
+	defer cancel()
+	m := c.Map(ids)
+	if len(m) == 0 && len(ids) > 0 {
+		return ErrNoShards
+	}
+	wg := sync.WaitGroup{}
+	errCh := make(chan error, len(m))
+	for s, i := range m {
+		wg.Add(1)
+		go func(ids []KeyType, sh Shard[ConnType]) {
+			defer wg.Done()
+			if err := c.runShard(ctx, sh, func(ctx context.Context) error {
+				return fn(ctx, ids, sh)
+			}); err != nil {
+				errCh <- err
+				cancel()
+			}
+		}(i, s)
+	}
+	wg.Wait()
+	close(errCh)
+	return <-errCh
+}
+
+// AddShard adds a shard to the cluster keeping the shard order. The shard
+// id must be positive and unique within the cluster.
+func (c *cluster[KeyType, ConnType]) AddShard(s Shard[ConnType]) error {
+	if s == nil {
+		return errors.New("shard cannot be nil")
+	}
+	if s.ID() <= 0 {
+		return &ErrInvalidShard{s.ID(), "invalid id"}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, e := range c.list {
+		if e.ID() == s.ID() {
+			return fmt.Errorf("shard %d already exists", s.ID())
+		}
+	}
+	i := sort.Search(len(c.list), func(i int) bool {
+		return c.less(s, c.list[i])
+	})
+	list := make([]Shard[ConnType], 0, len(c.list)+1)
+	list = append(list, c.list[:i]...)
+	list = append(list, s)
+	list = append(list, c.list[i:]...)
+	c.list = list
+	return nil
+}
+
+// RemoveShardsWhere removes and returns all shards matching pred.
+func (c *cluster[KeyType, ConnType]) RemoveShardsWhere(pred func(Shard[ConnType]) bool) []Shard[ConnType] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var removed, kept []Shard[ConnType]
+	for _, s := range c.list {
+		if pred(s) {
+			removed = append(removed, s)
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	c.list = kept
+	for _, s := range removed {
+		delete(c.down, s.ID())
+		delete(c.draining, s.ID())
+	}
+	return removed
+}
+
+// less orders shards by Config.ShardLess, or by id when it's not set.
+func (c *cluster[KeyType, ConnType]) less(a, b Shard[ConnType]) bool {
+	if c.shardLess == nil {
+		return a.ID() < b.ID()
+	}
+	return c.shardLess(configOf(a), configOf(b))
+}
+
+// configOf returns the config s was connected with, or only its id for
+// shards not created by Connect.
+func configOf[ConnType any](s Shard[ConnType]) ShardConfig {
+	if o, ok := s.(ownShard); ok {
+		return o.shardConfig()
+	}
+	return ShardConfig{ID: s.ID()}
+}
+
+// RemoveShard removes a shard from the cluster by id.
+func (c *cluster[KeyType, ConnType]) RemoveShard(id int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, s := range c.list {
+		if s.ID() == id {
+			list := make([]Shard[ConnType], 0, len(c.list)-1)
+			list = append(list, c.list[:i]...)
+			list = append(list, c.list[i+1:]...)
+			c.list = list
+			delete(c.down, id)
+			delete(c.draining, id)
+			return nil
+		}
+	}
+	return fmt.Errorf("shard %d not found", id)
+}
+
+// Ping checks all shards in parallel. Connections implementing Pinger
+// (e.g. *sql.DB) are pinged with PingContext, other connections are
+// checked with Config.PingFunc. Failures are joined and annotated with
+// shard id.
+func (c *cluster[KeyType, ConnType]) Ping(ctx context.Context) error {
+	return eachJoin(c.All(), func(s Shard[ConnType]) error {
+		conn, err := s.ConnE()
+		if err != nil {
+			return err
+		}
+		if p, ok := any(conn).(Pinger); ok {
+			return p.PingContext(ctx)
+		}
+		if c.ping != nil {
+			return c.ping(ctx, conn)
+		}
+		return errors.New("ping is not supported, set Config.PingFunc")
+	})
+}
+
+// HealthCheckAll runs Config.HealthCheck on all shards in parallel and
+// returns joined per-shard errors. Returns ErrNoHealthCheck when
+// Config.HealthCheck is not set.
+func (c *cluster[KeyType, ConnType]) HealthCheckAll(ctx context.Context) error {
+	if c.health == nil {
+		return ErrNoHealthCheck
+	}
+	return eachJoin(c.All(), func(s Shard[ConnType]) error {
+		conn, err := s.ConnE()
+		if err != nil {
+			return err
+		}
+		return c.health(ctx, conn)
+	})
+}
+
+// Warmup establishes the connections of all shards and health checks them.
+func (c *cluster[KeyType, ConnType]) Warmup(ctx context.Context) error {
+	return eachJoin(c.All(), func(s Shard[ConnType]) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		conn, err := s.ConnE()
+		if err != nil {
+			return err
+		}
+		if c.health == nil {
+			return nil
+		}
+		return c.health(ctx, conn)
+	})
+}
+
+// eachJoin runs fn on each shard in parallel and returns all errors joined,
+// each prefixed with the id of the shard it came from.
+func eachJoin[ConnType any](list []Shard[ConnType], fn func(s Shard[ConnType]) error) error {
+	var (
+		errs = make([]error, len(list))
+		wg   sync.WaitGroup
+	)
+	for i, s := range list {
+		wg.Add(1)
+		go func(i int, s Shard[ConnType]) {
+			defer wg.Done()
+			if err := safeCall(s.ID(), func() error { return fn(s) }); err != nil {
+				errs[i] = &ShardError{s.ID(), err}
+			}
+		}(i, s)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// ShardError is an error which occurred on a shard.
+type ShardError struct {
+	ID  int64
+	Err error
+}
+
+// Error returns the error message prefixed with the shard id.
+func (e *ShardError) Error() string {
+	return fmt.Sprintf("shard %d: %v", e.ID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ShardError) Unwrap() error {
+	return e.Err
+}
+
+// shardError wraps err into ShardError unless it's nil.
+func shardError(id int64, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ShardError{id, err}
+}
+
+// safeCall calls fn converting a panic to an error.
+func safeCall(id int64, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic in shard %d: %v", id, r)
+		}
+	}()
+	return fn()
+}
+
+// Reconnect re-establishes connections of a shard created by Connect
+// using its stored address.
+func (c *cluster[KeyType, ConnType]) Reconnect(ctx context.Context, id int64) error {
+	if c.dial == nil {
+		return errors.New("reconnect is not supported, cluster is not created by Connect")
+	}
+	old, ok := c.byID(id).(ownShard)
+	if !ok {
+		return fmt.Errorf("shard %d not found or has no address", id)
+	}
+	sc := old.shardConfig()
+	if sc.Addr == "" {
+		return fmt.Errorf("shard %d not found or has no address", id)
+	}
+	return c.replace(ctx, old, sc)
+}
+
+// replace dials sc and swaps old with the new shard, then closes old
+// connections. On failure, the old shard is kept.
+func (c *cluster[KeyType, ConnType]) replace(ctx context.Context, old ownShard, sc ShardConfig) error {
+	id := sc.ID
+	s, err := c.dial(ctx, sc)
+	if err != nil {
+		return &ShardError{id, err}
+	}
+	c.mu.Lock()
+	replaced := false
+	for i := range c.list {
+		if c.list[i] == old.(Shard[ConnType]) {
+			list := c.snapshot()
+			list[i] = s
+			c.list = list
+			replaced = true
+			break
+		}
+	}
+	c.mu.Unlock()
+	if !replaced {
+		_ = s.(ownShard).closeConns()
+		return fmt.Errorf("shard %d was changed while reconnecting", id)
+	}
+	return old.closeConns()
+}
+
+// byID returns shard by id or nil.
+func (c *cluster[KeyType, ConnType]) byID(id int64) Shard[ConnType] {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, s := range c.list {
+		if s.ID() == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// snapshot returns a copy of the shard list. Caller must hold c.mu.
+func (c *cluster[KeyType, ConnType]) snapshot() []Shard[ConnType] {
+	list := make([]Shard[ConnType], len(c.list))
+	copy(list, c.list)
+	return list
+}
+
+// Shard interface.
+type Shard[ConnType any] interface {
+	ID() int64
+	Conn() ConnType
+
+	// Addr returns the shard address with credentials masked. It's empty
+	// for shards created with NewShard.
+	Addr() string
+
+	// ConnE returns the connection or the error which occurred while
+	// establishing it. It is only meaningful in lazy mode (see Config.Lazy),
+	// where Conn returns a zero ConnType on failure.
+	ConnE() (ConnType, error)
+
+	// Meta returns a copy of ShardConfig.Meta the shard was created or last
+	// updated by Cluster.Watch with, e.g. to Filter shards by region. It's
+	// nil when there's none.
+	Meta() map[string]string
+}
+
+// NewShard returns a Shard with the given id and connection, e.g. to be
+// passed to Cluster.AddShard.
+func NewShard[ConnType any](id int64, conn ConnType) Shard[ConnType] {
+	return &shard[ConnType]{id: id, conn: conn}
+}
+
+type shard[ConnType any] struct {
+	id     int64
+	conn   ConnType
+	addr   string
+	name   string
+	mu     sync.RWMutex // guards meta, weight and tls, see setAttrs.
+	meta   map[string]string
+	weight int
+	tls    *tls.Config
+}
+
+// ID returns ConnIDType.
+func (s *shard[ConnType]) ID() int64 {
+	return s.id
+}
+
+// Conn returns database connection.
+func (s *shard[ConnType]) Conn() ConnType {
+	return s.conn
+}
+
+// Addr returns the shard address with credentials masked.
+func (s *shard[ConnType]) Addr() string {
+	return redact(s.addr)
+}
+
+// ConnE returns database connection and a nil error.
+func (s *shard[ConnType]) ConnE() (ConnType, error) {
+	return s.conn, nil
+}
+
+// ReplicatedShard is a Shard with a primary connection for writes and
+// read replicas. Shards configured with ShardConfig.ReadAddrs implement it,
+// Conn returns the primary connection.
+type ReplicatedShard[ConnType any] interface {
+	Shard[ConnType]
+
+	// WriteConn returns the primary connection.
+	WriteConn() ConnType
+
+	// ReadConn returns one of the read replicas, round-robin.
+	ReadConn() ConnType
+
+	// ReadConns returns all read replicas.
+	ReadConns() []ConnType
+}
+
+// ReplicaSelector picks one of the read replicas of a shard, e.g. the one
+// with the lowest latency. replicas must not be modified.
+type ReplicaSelector[ConnType any] interface {
+	Pick(replicas []ConnType) ConnType
+}
+
+// ReplicaSelectorFunc is a func adapter for ReplicaSelector.
+type ReplicaSelectorFunc[ConnType any] func(replicas []ConnType) ConnType
+
+// Pick calls f(replicas).
+func (f ReplicaSelectorFunc[ConnType]) Pick(replicas []ConnType) ConnType {
+	return f(replicas)
+}
+
+type replicatedShard[ConnType any] struct {
+	shard[ConnType]
+	reads     []ConnType
+	readAddrs []string
+	next      atomic.Uint64
+}
+
+// WriteConn returns the primary connection.
+func (s *replicatedShard[ConnType]) WriteConn() ConnType {
+	return s.conn
+}
+
+// ReadConn returns one of the read replicas, round-robin.
+func (s *replicatedShard[ConnType]) ReadConn() ConnType {
+	return s.reads[(s.next.Add(1)-1)%uint64(len(s.reads))]
+}
+
+// ReadConns returns all read replicas.
+func (s *replicatedShard[ConnType]) ReadConns() []ConnType {
+	reads := make([]ConnType, len(s.reads))
+	copy(reads, s.reads)
+	return reads
+}
+
+// lazyShard establishes its connection on first access.
+type lazyShard[ConnType any] struct {
+	id     int64
+	addr   string
+	name   string
+	mu     sync.RWMutex // guards meta, weight and tls, see setAttrs.
+	meta   map[string]string
+	weight int
+	tls    *tls.Config
+	dial   func() (ConnType, error)
+	once   sync.Once
+	done   atomic.Bool
+	conn   ConnType
+	err    error
+}
+
+func newLazyShard[ConnType any](id int64, addr string, dial func() (ConnType, error)) *lazyShard[ConnType] {
+	return &lazyShard[ConnType]{id: id, addr: addr, dial: dial}
+}
+
+// ID returns shard id.
+func (s *lazyShard[ConnType]) ID() int64 {
+	return s.id
+}
+
+// Addr returns the shard address with credentials masked.
+func (s *lazyShard[ConnType]) Addr() string {
+	return redact(s.addr)
+}
+
+// Conn returns database connection, establishing it on first call.
+// Returns a zero ConnType if connection failed, use ConnE to get the error.
+func (s *lazyShard[ConnType]) Conn() ConnType {
+	conn, _ := s.ConnE()
+	return conn
+}
+
+// ConnE returns database connection, establishing it on first call.
+// Connection is attempted only once, the error is returned on every
+// subsequent call.
+func (s *lazyShard[ConnType]) ConnE() (ConnType, error) {
+	s.once.Do(func() {
+		s.conn, s.err = s.dial()
+		s.done.Store(true)
+	})
+	return s.conn, s.err
+}
+
+// shardConfig returns the config the shard was connected with. Address is
+// empty for shards created with NewShard.
+func (s *shard[ConnType]) shardConfig() ShardConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return ShardConfig{ID: s.id, Addr: s.addr, Meta: s.meta, Weight: s.weight, TLS: s.tls, name: s.name}
+}
+
+func (s *replicatedShard[ConnType]) shardConfig() ShardConfig {
+	sc := s.shard.shardConfig()
+	sc.ReadAddrs = s.readAddrs
+	return sc
+}
+
+func (s *lazyShard[ConnType]) shardConfig() ShardConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return ShardConfig{ID: s.id, Addr: s.addr, Meta: s.meta, Weight: s.weight, TLS: s.tls, name: s.name}
+}
+
+// setAttrs updates the shard attributes which don't require reconnecting.
+func (s *shard[ConnType]) setAttrs(sc ShardConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta, s.weight, s.tls = copyMeta(sc.Meta), sc.Weight, sc.TLS
+}
+
+func (s *lazyShard[ConnType]) setAttrs(sc ShardConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meta, s.weight, s.tls = copyMeta(sc.Meta), sc.Weight, sc.TLS
+}
+
+// closeConns closes all connections of the shard which implement io.Closer.
+func (s *shard[ConnType]) closeConns() error {
+	return closeConn(s.conn)
+}
+
+func (s *replicatedShard[ConnType]) closeConns() error {
+	errs := []error{closeConn(s.conn)}
+	for _, r := range s.reads {
+		errs = append(errs, closeConn(r))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *lazyShard[ConnType]) closeConns() error {
+	if !s.done.Load() || s.err != nil {
+		return nil
+	}
+	return closeConn(s.conn)
+}
+
+// ownShard is implemented by shards created by Connect.
+type ownShard interface {
+	shardConfig() ShardConfig
+	setAttrs(sc ShardConfig)
+	closeConns() error
+}
+
+// Meta returns a copy of the shard metadata.
+func (s *shard[ConnType]) Meta() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyMeta(s.meta)
+}
+
+// Meta returns a copy of the shard metadata.
+func (s *lazyShard[ConnType]) Meta() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyMeta(s.meta)
+}
+
+// copyMeta returns a copy of m, nil when m is empty.
+func copyMeta(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	res := make(map[string]string, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	return res
+}
+
+func closeConn(conn any) error {
+	if c, ok := conn.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Hash interface.
+type Hash[KeyType ID] interface {
+	Sum(key KeyType) uint64
+}
+
+// NewDefaultHash returns crc64 based Hash. Keys are encoded with encoder
+// when one is given, otherwise integers are encoded as decimal text and
+// strings and bytes as is.
+func NewDefaultHash[KeyType ID](encoder ...KeyEncoder[KeyType]) Hash[KeyType] {
+	h := &defaultHash[KeyType]{t: crc64.MakeTable(crc64.ISO)}
+	if len(encoder) > 0 {
+		h.enc = encoder[0]
+	}
+	return h
+}
+
+type defaultHash[KeyType ID] struct {
+	t   *crc64.Table
+	enc KeyEncoder[KeyType]
+}
+
+// Sum of id.
+func (h *defaultHash[KeyType]) Sum(id KeyType) uint64 {
+	if h.enc != nil {
+		return crc64.Checksum(h.enc.Encode(id), h.t)
+	}
+	var buf [20]byte
+	switch i := any(id).(type) {
+	case int64:
+		return crc64.Checksum(strconv.AppendInt(buf[:0], i, 10), h.t)
+	case uint64:
+		return crc64.Checksum(strconv.AppendUint(buf[:0], i, 10), h.t)
+	case string:
+		// the conversion doesn't allocate as the bytes don't escape
+		return crc64.Checksum([]byte(i), h.t)
+	}
+	return crc64.Checksum(encodeKey(id), h.t)
+}
+
+// KeyEncoder encodes keys to bytes fed to Hash, replacing the built-in
+// encoding. It allows full control over how e.g. a serialized composite
+// key maps to shards.
+type KeyEncoder[KeyType ID] interface {
+	Encode(key KeyType) []byte
+}
+
+// KeyEncoderFunc is a func adapter for KeyEncoder.
+type KeyEncoderFunc[KeyType ID] func(key KeyType) []byte
+
+// Encode calls f(key).
+func (f KeyEncoderFunc[KeyType]) Encode(key KeyType) []byte {
+	return f(key)
+}
+
+// encodeKey returns the byte representation of id hashed by the built-in
+// hashes: integers are encoded as decimal text, strings and bytes as is.
+func encodeKey[KeyType ID](id KeyType) []byte {
+	var idb []byte
+	switch i := any(id).(type) {
+	case int64:
+		idb = strconv.AppendInt(idb, i, 10)
+	case uint64:
+		idb = strconv.AppendUint(idb, i, 10)
+	case string:
+		idb = []byte(i)
+	case []byte:
+		idb = i
+	}
+	return idb
 }
 
 // Strategy interface.
 type Strategy[KeyType ID, ConnType any] interface {
 
-	// Find shard by key.
+	// Find shard by key. Returns nil when shards is empty instead of
+	// panicking, callers passing a list which may be empty must check.
 	Find(key KeyType, shards []Shard[ConnType]) Shard[ConnType]
+
+	// FindByHash finds shard by a precomputed hash of key, as if Find was
+	// called with a key hashing to sum. Returns nil when shards is empty.
+	FindByHash(sum uint64, shards []Shard[ConnType]) Shard[ConnType]
+}
+
+// KeyHasher is implemented by strategies which place keys by a hash, so
+// Cluster.HashOf can report it. HashOf returns the sum FindByHash places
+// the same as Find places key.
+type KeyHasher[KeyType ID] interface {
+	HashOf(key KeyType) uint64
 }
 
+// NewDefaultStrategy returns Strategy which picks a shard by hash modulo
+// number of shards. When hash is nil, NewDefaultHash is used with encoder
+// if one is given.
 func NewDefaultStrategy[KeyType ID, ConnType any](
 	hash Hash[KeyType],
+	encoder ...KeyEncoder[KeyType],
 ) Strategy[KeyType, ConnType] {
 	if hash == nil {
-		hash = NewDefaultHash[KeyType]()
+		hash = NewDefaultHash[KeyType](encoder...)
 	}
-	return &defaultStrategy[KeyType, ConnType]{hash}
+	return &defaultStrategy[KeyType, ConnType]{hash: hash}
 }
 
 type defaultStrategy[KeyType ID, ConnType any] struct {
-	hash Hash[KeyType]
+	hash    Hash[KeyType]
+	extract func(KeyType) KeyType
+	sorted  atomic.Pointer[sortedShards[ConnType]]
 }
 
+// Find picks shard by hash of key modulo number of shards. Shards are
+// indexed in id order regardless of the order they're passed in, so
+// placement is stable across restarts.
 func (c *defaultStrategy[KeyType, ConnType]) Find(
 	key KeyType,
 	shards []Shard[ConnType],
 ) Shard[ConnType] {
-	return shards[int(c.hash.Sum(key)%uint64(len(shards)))]
+	return c.FindByHash(c.HashOf(key), shards)
+}
+
+// HashOf returns the hash of key, after Config.KeyExtractor if set.
+func (c *defaultStrategy[KeyType, ConnType]) HashOf(key KeyType) uint64 {
+	if c.extract != nil {
+		key = c.extract(key)
+	}
+	return c.hash.Sum(key)
+}
+
+// FindByHash picks shard by sum modulo number of shards.
+func (c *defaultStrategy[KeyType, ConnType]) FindByHash(
+	sum uint64,
+	shards []Shard[ConnType],
+) Shard[ConnType] {
+	if len(shards) == 0 {
+		return nil
+	}
+	shards = c.sortedShards(shards)
+	return shards[int(sum%uint64(len(shards)))]
+}
+
+// sortedShards is a shard list sorted by id, cached by the identity of the
+// list it was made from.
+type sortedShards[ConnType any] struct {
+	first  *Shard[ConnType]
+	n      int
+	shards []Shard[ConnType]
+}
+
+// sortedShards returns shards sorted by id. The result is cached by the
+// identity (backing array and length) of shards, so a list must not be
+// modified in place once passed to Find. Cluster never does that.
+func (c *defaultStrategy[KeyType, ConnType]) sortedShards(shards []Shard[ConnType]) []Shard[ConnType] {
+	if len(shards) == 0 {
+		return shards
+	}
+	if s := c.sorted.Load(); s != nil && s.first == &shards[0] && s.n == len(shards) {
+		return s.shards
+	}
+	sorted := shards
+	if !sort.SliceIsSorted(shards, func(i, j int) bool { return shards[i].ID() < shards[j].ID() }) {
+		sorted = make([]Shard[ConnType], len(shards))
+		copy(sorted, shards)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID() < sorted[j].ID() })
+	}
+	if debug {
+		checkSorted(sorted)
+	}
+	c.sorted.Store(&sortedShards[ConnType]{&shards[0], len(shards), sorted})
+	return sorted
 }
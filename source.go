@@ -0,0 +1,59 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+)
+
+// ConfigSource loads shard configs, e.g. from a KV store like Consul or
+// etcd. Only env and file sources are provided by this package.
+type ConfigSource interface {
+	Load(ctx context.Context) ([]ShardConfig, error)
+}
+
+// ConfigSourceFunc is a func adapter for ConfigSource.
+type ConfigSourceFunc func(ctx context.Context) ([]ShardConfig, error)
+
+// Load calls f(ctx).
+func (f ConfigSourceFunc) Load(ctx context.Context) ([]ShardConfig, error) {
+	return f(ctx)
+}
+
+// EnvSource returns ConfigSource loading shards with ShardsConfigFromEnvWith.
+func EnvSource(opts EnvOptions) ConfigSource {
+	return ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+		shards := ShardsConfigFromEnvWith(opts)
+		if len(shards) == 0 {
+			return nil, errors.New("no shards found in environment")
+		}
+		return shards, nil
+	})
+}
+
+// FileSource returns ConfigSource loading shards with ShardsConfigFromFile.
+func FileSource(path string) ConfigSource {
+	return ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+		return ShardsConfigFromFile(path)
+	})
+}
+
+// ConnectFromSource loads shard configs from source and connects to them
+// with Connect. cfg.Shards must be empty, cfg.Context defaults to ctx.
+func ConnectFromSource[KeyType ID, ConnType any](
+	ctx context.Context,
+	source ConfigSource,
+	cfg Config[KeyType, ConnType],
+) (Cluster[KeyType, ConnType], error) {
+	if len(cfg.Shards) > 0 {
+		return nil, errors.New("shards must be loaded from the source only")
+	}
+	shards, err := source.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Shards = shards
+	if cfg.Context == nil {
+		cfg.Context = ctx
+	}
+	return Connect(cfg)
+}
@@ -0,0 +1,74 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConnectFromSource(t *testing.T) {
+	connect := func(_ context.Context, addr string) (string, error) {
+		return "conn-" + addr, nil
+	}
+	errSource := errors.New("source")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "shards.json")
+	if err := os.WriteFile(file, []byte(`[{"id":1,"dsn":"f1"},{"id":2,"dsn":"f2"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SOURCE_TEST_SHARD_ADDRESS_1", "e1")
+	tests := []struct {
+		name    string
+		source  ConfigSource
+		shards  []ShardConfig
+		want    []string
+		wantErr bool
+	}{
+		{
+			"fake",
+			ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+				return []ShardConfig{{ID: 3, Addr: "3"}, {ID: 1, Addr: "1"}, {ID: 2, Addr: "2"}}, nil
+			}),
+			nil,
+			[]string{"conn-1", "conn-2", "conn-3"},
+			false,
+		},
+		{"file", FileSource(file), nil, []string{"conn-f1", "conn-f2"}, false},
+		{"env", EnvSource(EnvOptions{Prefix: "SOURCE_TEST"}), nil, []string{"conn-e1"}, false},
+		{"empty env", EnvSource(EnvOptions{Prefix: "SOURCE_TEST_MISSING"}), nil, nil, true},
+		{
+			"error",
+			ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+				return nil, errSource
+			}),
+			nil,
+			nil,
+			true,
+		},
+		{"shards in config", FileSource(file), []ShardConfig{{ID: 1, Addr: "1"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ConnectFromSource(context.Background(), tt.source, Config[uint64, string]{
+				Connect: connect,
+				Shards:  tt.shards,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ConnectFromSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			var got []string
+			for _, s := range c.All() {
+				got = append(got, s.Conn())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ConnectFromSource() conns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
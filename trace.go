@@ -0,0 +1,83 @@
+package sharding
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer starts spans around fan-out calls. It is small enough to be
+// adapted to OpenTelemetry or any other tracing library without adding
+// a dependency. Start returns a derived context and a func which finishes
+// the span with the error returned by the traced operation (nil on success).
+//
+// Spans started for a single shard have the shard id stored in ctx, adapters
+// can read it with ShardIDFromContext and record it as an attribute.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, func(error))
+}
+
+type shardIDKey struct{}
+
+// ShardIDFromContext returns the id of the shard a span was started for.
+func ShardIDFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(shardIDKey{}).(int64)
+	return id, ok
+}
+
+func finishNoop(error) {}
+
+// startSpan starts a span when tracer is set, otherwise it's a no-op.
+func (c *cluster[KeyType, ConnType]) startSpan(ctx context.Context, name string) (context.Context, func(error)) {
+	if c.tracer == nil {
+		return ctx, finishNoop
+	}
+	return c.tracer.Start(ctx, name)
+}
+
+// runShard runs fn within a child span of shard s when tracer is set.
+// fn is not called when ctx is done, the breaker is open or the shard is
+// down and skipDownShards is set. ctx passed to
+// fn expires after shardTimeout when it's set. Panics in fn
+// are recovered and returned as errors, errors are wrapped into ShardError.
+func (c *cluster[KeyType, ConnType]) runShard(
+	ctx context.Context,
+	s Shard[ConnType],
+	fn func(ctx context.Context) error,
+) error {
+	if err := ctx.Err(); err != nil {
+		return shardError(s.ID(), err)
+	}
+	if c.skipDownShards && !c.IsShardUp(s.ID()) {
+		return nil
+	}
+	if c.breaker != nil && !c.breaker.Allow(s.ID()) {
+		return shardError(s.ID(), ErrShardOpen)
+	}
+	defer c.ops.start(s.ID())()
+	// DrainShard may have returned between the routing of s and the start
+	// above, when nothing was counted in flight yet
+	if c.isDraining(s.ID()) {
+		return shardError(s.ID(), ErrShardDraining)
+	}
+	if c.shardTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.shardTimeout)
+		defer cancel()
+	}
+	var (
+		err   error
+		start = time.Now()
+	)
+	if c.tracer == nil {
+		err = safeCall(s.ID(), func() error { return fn(ctx) })
+	} else {
+		ctx, finish := c.tracer.Start(context.WithValue(ctx, shardIDKey{}, s.ID()), "sharding.Shard")
+		err = safeCall(s.ID(), func() error { return fn(ctx) })
+		finish(err)
+	}
+	c.metrics.called(s.ID(), time.Since(start), err)
+	if c.breaker != nil {
+		c.breaker.Record(s.ID(), err)
+	}
+	return shardError(s.ID(), err)
+}
@@ -0,0 +1,120 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+)
+
+type span struct {
+	name    string
+	shardID int64
+	err     error
+}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []span
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, func(error)) {
+	id, _ := ShardIDFromContext(ctx)
+	return ctx, func(err error) {
+		r.mu.Lock()
+		r.spans = append(r.spans, span{name, id, err})
+		r.mu.Unlock()
+	}
+}
+
+func (r *recordingTracer) sorted() []span {
+	sort.Slice(r.spans, func(i, j int) bool {
+		if r.spans[i].name != r.spans[j].name {
+			return r.spans[i].name < r.spans[j].name
+		}
+		return r.spans[i].shardID < r.spans[j].shardID
+	})
+	return r.spans
+}
+
+func Test_cluster_tracer(t *testing.T) {
+	errShard := errors.New("error")
+	newCluster := func(tr Tracer) *cluster[uint64, struct{}] {
+		return &cluster[uint64, struct{}]{
+			list: []Shard[struct{}]{
+				NewShard(1, struct{}{}),
+				NewShard(2, struct{}{}),
+				NewShard(3, struct{}{}),
+			},
+			calc:   NewDefaultStrategy[uint64, struct{}](nil),
+			tracer: tr,
+		}
+	}
+	t.Run("each", func(t *testing.T) {
+		tr := &recordingTracer{}
+		err := newCluster(tr).EachCtx(context.Background(), func(ctx context.Context, s Shard[struct{}]) error {
+			if id, ok := ShardIDFromContext(ctx); !ok || id != s.ID() {
+				t.Errorf("ShardIDFromContext() = %v, %v, want %v", id, ok, s.ID())
+			}
+			if s.ID() == 2 {
+				return errShard
+			}
+			return nil
+		})
+		if !errors.Is(err, errShard) {
+			t.Errorf("EachCtx() error = %v, want %v", err, errShard)
+		}
+		want := []span{
+			{"sharding.Each", 0, errShard},
+			{"sharding.Shard", 1, nil},
+			{"sharding.Shard", 2, errShard},
+			{"sharding.Shard", 3, nil},
+		}
+		if got := tr.sorted(); !equalSpans(got, want) {
+			t.Errorf("spans = %v, want %v", got, want)
+		}
+	})
+	t.Run("by keys", func(t *testing.T) {
+		tr := &recordingTracer{}
+		err := newCluster(tr).ByKeys([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, func([]uint64, Shard[struct{}]) error {
+			return nil
+		})
+		if err != nil {
+			t.Errorf("ByKeys() error = %v", err)
+		}
+		want := []span{
+			{"sharding.ByKeys", 0, nil},
+			{"sharding.Shard", 1, nil},
+			{"sharding.Shard", 2, nil},
+			{"sharding.Shard", 3, nil},
+		}
+		if got := tr.sorted(); !equalSpans(got, want) {
+			t.Errorf("spans = %v, want %v", got, want)
+		}
+	})
+	t.Run("no tracer", func(t *testing.T) {
+		err := newCluster(nil).EachCtx(context.Background(), func(ctx context.Context, _ Shard[struct{}]) error {
+			if _, ok := ShardIDFromContext(ctx); ok {
+				t.Error("ShardIDFromContext() ok = true without tracer")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("EachCtx() error = %v", err)
+		}
+	})
+}
+
+func equalSpans(a, b []span) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].name != b[i].name || a[i].shardID != b[i].shardID ||
+			(a[i].err == nil) != (b[i].err == nil) || !errors.Is(a[i].err, b[i].err) {
+			return false
+		}
+	}
+	return true
+}
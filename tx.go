@@ -0,0 +1,41 @@
+package sharding
+
+import "errors"
+
+// Tx is a transaction, e.g. *sql.Tx.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// Transact begins a transaction on conn, runs fn and commits it. The
+// transaction is rolled back when fn returns an error or panics, the panic
+// is propagated after the rollback. Use it with the shard owning a key:
+//
+//	err := sharding.Transact(cluster.One(key).Conn(),
+//		func(db *sql.DB) (*sql.Tx, error) { return db.Begin() },
+//		func(tx *sql.Tx) error { ... },
+//	)
+func Transact[ConnType any, TxType Tx](
+	conn ConnType,
+	begin func(ConnType) (TxType, error),
+	fn func(TxType) error,
+) (err error) {
+	tx, err := begin(conn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err = fn(tx); err != nil {
+		if rErr := tx.Rollback(); rErr != nil {
+			return errors.Join(err, rErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,112 @@
+package sharding
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type dummyTx struct {
+	calls       []string
+	commitErr   error
+	rollbackErr error
+}
+
+func (tx *dummyTx) Commit() error {
+	tx.calls = append(tx.calls, "commit")
+	return tx.commitErr
+}
+
+func (tx *dummyTx) Rollback() error {
+	tx.calls = append(tx.calls, "rollback")
+	return tx.rollbackErr
+}
+
+func TestTransact(t *testing.T) {
+	errFn := errors.New("fn")
+	errDB := errors.New("db")
+	tests := []struct {
+		name      string
+		tx        *dummyTx
+		beginErr  error
+		fn        func(*dummyTx) error
+		wantCalls []string
+		wantErr   []error
+	}{
+		{
+			"commit",
+			&dummyTx{},
+			nil,
+			func(tx *dummyTx) error { tx.calls = append(tx.calls, "fn"); return nil },
+			[]string{"fn", "commit"},
+			nil,
+		},
+		{
+			"commit error",
+			&dummyTx{commitErr: errDB},
+			nil,
+			func(*dummyTx) error { return nil },
+			[]string{"commit"},
+			[]error{errDB},
+		},
+		{
+			"rollback",
+			&dummyTx{},
+			nil,
+			func(*dummyTx) error { return errFn },
+			[]string{"rollback"},
+			[]error{errFn},
+		},
+		{
+			"rollback error",
+			&dummyTx{rollbackErr: errDB},
+			nil,
+			func(*dummyTx) error { return errFn },
+			[]string{"rollback"},
+			[]error{errFn, errDB},
+		},
+		{
+			"begin error",
+			&dummyTx{},
+			errDB,
+			func(*dummyTx) error { return nil },
+			nil,
+			[]error{errDB},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Transact(struct{}{}, func(struct{}) (*dummyTx, error) {
+				return tt.tx, tt.beginErr
+			}, tt.fn)
+			if (err != nil) != (len(tt.wantErr) > 0) {
+				t.Errorf("Transact() error = %v, want %v", err, tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("Transact() error = %v, want %v", err, want)
+				}
+			}
+			if !reflect.DeepEqual(tt.tx.calls, tt.wantCalls) {
+				t.Errorf("Transact() calls = %v, want %v", tt.tx.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTransact_panic(t *testing.T) {
+	tx := &dummyTx{}
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("Transact() panic = %v, want boom", p)
+		}
+		if !reflect.DeepEqual(tx.calls, []string{"rollback"}) {
+			t.Errorf("Transact() calls = %v, want [rollback]", tx.calls)
+		}
+	}()
+	_ = Transact(struct{}{}, func(struct{}) (*dummyTx, error) {
+		return tx, nil
+	}, func(*dummyTx) error {
+		panic("boom")
+	})
+}
@@ -0,0 +1,160 @@
+package sharding
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"time"
+)
+
+// TopologyChange lists ids of shards changed by Cluster.Watch.
+type TopologyChange struct {
+	Added    []int64
+	Removed  []int64
+	Replaced []int64 // reconnected because their address changed.
+	Updated  []int64 // weight, metadata or TLS updated without reconnecting.
+}
+
+// Watch polls source and applies topology changes until ctx is done.
+func (c *cluster[KeyType, ConnType]) Watch(ctx context.Context, source ConfigSource, interval time.Duration) error {
+	if c.dial == nil {
+		return errors.New("watch is not supported, cluster is not created by Connect")
+	}
+	if interval <= 0 {
+		return errors.New("watch interval must be positive")
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := c.sync(ctx, source); err != nil && ctx.Err() == nil {
+			c.log().Printf("sharding: watch failed err=%v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// sync loads shard configs from source and applies the difference.
+func (c *cluster[KeyType, ConnType]) sync(ctx context.Context, source ConfigSource) error {
+	configs, err := source.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		return ErrNoShards
+	}
+	if err = checkShards(configs, c.allowDuplicateAddrs, c.requireContiguousIDs); err != nil {
+		return err
+	}
+	want := make(map[int64]ShardConfig, len(configs))
+	for _, sc := range configs {
+		if err = sc.valid(); err != nil {
+			return err
+		}
+		want[sc.ID] = sc
+	}
+	var (
+		change  TopologyChange
+		errs    []error
+		removed []Shard[ConnType]
+	)
+	// add and replace shards before removing any, so the cluster is never
+	// empty in between
+	for _, s := range c.All() {
+		sc, ok := want[s.ID()]
+		delete(want, s.ID())
+		if !ok {
+			removed = append(removed, s)
+			continue
+		}
+		o, ok := s.(ownShard)
+		if !ok {
+			continue
+		}
+		cur := o.shardConfig()
+		if sc.TLS == nil {
+			// sources like FileSource and EnvSource can't carry TLS
+			sc.TLS = cur.TLS
+		}
+		if sameConns(cur, sc) {
+			if !sameAttrs(cur, sc) {
+				o.setAttrs(sc)
+				change.Updated = append(change.Updated, s.ID())
+			}
+			continue
+		}
+		if err = c.replace(ctx, o, sc); err == nil {
+			change.Replaced = append(change.Replaced, s.ID())
+		}
+		errs = append(errs, err)
+	}
+	if len(change.Updated) > 0 && c.shardLess != nil {
+		// updated attributes may move shards in Config.ShardLess order,
+		// AddShard relies on it
+		c.resort()
+	}
+	for _, sc := range configs {
+		if _, ok := want[sc.ID]; !ok {
+			continue
+		}
+		s, err := c.dial(ctx, sc)
+		if err == nil {
+			if err = c.AddShard(s); err != nil {
+				_ = s.(ownShard).closeConns()
+			}
+		}
+		if err != nil {
+			errs = append(errs, shardError(sc.ID, err))
+			continue
+		}
+		change.Added = append(change.Added, sc.ID)
+	}
+	for _, s := range removed {
+		if err = c.RemoveShard(s.ID()); err == nil {
+			change.Removed = append(change.Removed, s.ID())
+			if o, ok := s.(ownShard); ok {
+				err = o.closeConns()
+			}
+		}
+		errs = append(errs, shardError(s.ID(), err))
+	}
+	if c.onTopologyChange != nil && len(change.Added)+len(change.Removed)+len(change.Replaced)+len(change.Updated) > 0 {
+		c.onTopologyChange(change)
+	}
+	return errors.Join(errs...)
+}
+
+// resort restores shard order after shard attributes changed.
+func (c *cluster[KeyType, ConnType]) resort() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := c.snapshot()
+	sort.SliceStable(list, func(i, j int) bool {
+		return c.less(list[i], list[j])
+	})
+	c.list = list
+}
+
+// sameConns reports whether a and b configure the same connections.
+func sameConns(a, b ShardConfig) bool {
+	return a.Addr == b.Addr && slices.Equal(a.ReadAddrs, b.ReadAddrs)
+}
+
+// sameAttrs reports whether a and b have the same weight, metadata and TLS.
+// A TLS change is used by the next reconnect.
+func sameAttrs(a, b ShardConfig) bool {
+	return a.weight() == b.weight() && a.TLS == b.TLS && maps.Equal(a.Meta, b.Meta)
+}
+
+// log returns the cluster logger, a no-op one when it's not set.
+func (c *cluster[KeyType, ConnType]) log() Logger {
+	if c.logger == nil {
+		return nopLogger{}
+	}
+	return c.logger
+}
@@ -0,0 +1,256 @@
+package sharding
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_cluster_Watch(t *testing.T) {
+	var mu sync.Mutex
+	c, err := Connect(Config[uint64, *dummyConn]{
+		Connect: func(_ context.Context, addr string) (*dummyConn, error) {
+			return &dummyConn{addr: addr}, nil
+		},
+		Shards: []ShardConfig{{ID: 1, Addr: "1"}, {ID: 2, Addr: "2"}},
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	polls := [][]ShardConfig{
+		{{ID: 1, Addr: "1"}, {ID: 2, Addr: "2"}, {ID: 3, Addr: "3"}},
+		{{ID: 1, Addr: "1b"}, {ID: 3, Addr: "3"}},
+	}
+	var (
+		poll    int
+		changes = make(chan TopologyChange)
+	)
+	source := ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if poll >= len(polls) {
+			return polls[len(polls)-1], nil
+		}
+		poll++
+		return polls[poll-1], nil
+	})
+	c.(*cluster[uint64, *dummyConn]).onTopologyChange = func(change TopologyChange) {
+		changes <- change
+	}
+	old := c.All()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- c.Watch(ctx, source, time.Millisecond)
+	}()
+	want := []TopologyChange{
+		{Added: []int64{3}},
+		{Removed: []int64{2}, Replaced: []int64{1}},
+	}
+	for _, w := range want {
+		select {
+		case got := <-changes:
+			if !reflect.DeepEqual(got, w) {
+				t.Errorf("OnTopologyChange() = %+v, want %+v", got, w)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("OnTopologyChange() not called, want %+v", w)
+		}
+	}
+	cancel()
+	select {
+	case err = <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Watch() error = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Watch() didn't stop after ctx was canceled")
+	}
+	var addrs []string
+	for _, s := range c.All() {
+		addrs = append(addrs, s.Conn().addr)
+	}
+	if want := []string{"1b", "3"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("Watch() shards = %v, want %v", addrs, want)
+	}
+	for i, s := range old {
+		if !s.Conn().closed {
+			t.Errorf("Watch() didn't close connection of shard %d", i+1)
+		}
+	}
+}
+
+func Test_cluster_sync_attrs(t *testing.T) {
+	c, err := Connect(Config[uint64, *dummyConn]{
+		Connect: func(_ context.Context, addr string) (*dummyConn, error) {
+			return &dummyConn{addr: addr}, nil
+		},
+		Shards: []ShardConfig{{ID: 1, Addr: "1", Weight: 1}, {ID: 2, Addr: "2"}},
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	var changes []TopologyChange
+	cl := c.(*cluster[uint64, *dummyConn])
+	cl.onTopologyChange = func(change TopologyChange) {
+		changes = append(changes, change)
+	}
+	old := c.All()
+	polls := [][]ShardConfig{
+		// weight 0 and 1 are the same
+		{{ID: 1, Addr: "1"}, {ID: 2, Addr: "2", Weight: 1}},
+		{{ID: 1, Addr: "1", Weight: 3}, {ID: 2, Addr: "2", Meta: map[string]string{"region": "eu"}}},
+	}
+	for _, p := range polls {
+		if err = cl.sync(context.Background(), ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+			return p, nil
+		})); err != nil {
+			t.Fatalf("sync() error = %v", err)
+		}
+	}
+	if want := []TopologyChange{{Updated: []int64{1, 2}}}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("OnTopologyChange() = %+v, want %+v", changes, want)
+	}
+	for i, s := range c.All() {
+		if s != old[i] || s.Conn().closed {
+			t.Errorf("sync() reconnected shard %d", s.ID())
+		}
+	}
+	if got := c.Stats().TotalWeight; got != 4 {
+		t.Errorf("Stats().TotalWeight = %d, want 4", got)
+	}
+	if got := c.All()[1].Meta(); !reflect.DeepEqual(got, map[string]string{"region": "eu"}) {
+		t.Errorf("Meta() = %v, want region eu", got)
+	}
+}
+
+func Test_cluster_sync_ShardLess(t *testing.T) {
+	c, err := Connect(Config[uint64, *dummyConn]{
+		Connect: func(_ context.Context, addr string) (*dummyConn, error) {
+			return &dummyConn{addr: addr}, nil
+		},
+		Shards: []ShardConfig{{ID: 1, Addr: "1", Weight: 1}, {ID: 2, Addr: "2", Weight: 2}},
+		ShardLess: func(a, b ShardConfig) bool {
+			return a.Weight > b.Weight
+		},
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err = c.(*cluster[uint64, *dummyConn]).sync(context.Background(), ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+		return []ShardConfig{
+			{ID: 1, Addr: "1", Weight: 5},
+			{ID: 2, Addr: "2", Weight: 2},
+			{ID: 3, Addr: "3", Weight: 3},
+		}, nil
+	})); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	var got []int64
+	for _, s := range c.All() {
+		got = append(got, s.ID())
+	}
+	if want := []int64{1, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+}
+
+func Test_cluster_sync_validation(t *testing.T) {
+	c, err := Connect(Config[uint64, *dummyConn]{
+		Connect: func(_ context.Context, addr string) (*dummyConn, error) {
+			return &dummyConn{addr: addr}, nil
+		},
+		Shards:               []ShardConfig{{ID: 1, Addr: "db"}, {ID: 2, Addr: "db"}},
+		AllowDuplicateAddrs:  true,
+		RequireContiguousIDs: true,
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	cl := c.(*cluster[uint64, *dummyConn])
+	apply := func(configs ...ShardConfig) error {
+		return cl.sync(context.Background(), ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+			return configs, nil
+		}))
+	}
+	if err = apply(ShardConfig{ID: 1, Addr: "db"}, ShardConfig{ID: 2, Addr: "db"}, ShardConfig{ID: 3, Addr: "db"}); err != nil {
+		t.Errorf("sync() of shared addresses error = %v", err)
+	}
+	if got := c.IDs(); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("IDs() = %v, want [1 2 3]", got)
+	}
+	if err = apply(ShardConfig{ID: 1, Addr: "db"}, ShardConfig{ID: 3, Addr: "db"}); err == nil {
+		t.Error("sync() of non contiguous ids error = nil, want an error")
+	}
+	if got := c.IDs(); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("IDs() after a rejected sync() = %v, want [1 2 3]", got)
+	}
+}
+
+func Test_cluster_sync_TLS(t *testing.T) {
+	tls1 := &tls.Config{ServerName: "one"}
+	c, err := Connect(Config[uint64, *dummyConn]{
+		Connect: func(_ context.Context, addr string) (*dummyConn, error) {
+			return &dummyConn{addr: addr}, nil
+		},
+		Shards: []ShardConfig{{ID: 1, Addr: "1", TLS: tls1}},
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	cl := c.(*cluster[uint64, *dummyConn])
+	old := c.All()[0]
+	apply := func(sc ShardConfig) {
+		t.Helper()
+		if err := cl.sync(context.Background(), ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+			return []ShardConfig{sc}, nil
+		})); err != nil {
+			t.Fatalf("sync() error = %v", err)
+		}
+	}
+	// a source without TLS keeps the current one
+	apply(ShardConfig{ID: 1, Addr: "1"})
+	if got := configOf(c.All()[0]).TLS; got != tls1 {
+		t.Errorf("sync() TLS = %v, want %v", got, tls1)
+	}
+	// a new TLS config doesn't reconnect
+	tls2 := &tls.Config{ServerName: "one"}
+	apply(ShardConfig{ID: 1, Addr: "1", TLS: tls2})
+	if got := configOf(c.All()[0]).TLS; got != tls2 {
+		t.Errorf("sync() TLS = %v, want %v", got, tls2)
+	}
+	if s := c.All()[0]; s != old || s.Conn().closed {
+		t.Error("sync() reconnected the shard")
+	}
+}
+
+func Test_cluster_sync_order(t *testing.T) {
+	var c Cluster[uint64, *dummyConn]
+	c, err := Connect(Config[uint64, *dummyConn]{
+		Connect: func(_ context.Context, addr string) (*dummyConn, error) {
+			if addr == "3" && c.Len() != 2 {
+				t.Errorf("Len() while adding shard 3 = %d, want 2", c.Len())
+			}
+			return &dummyConn{addr: addr}, nil
+		},
+		Shards: []ShardConfig{{ID: 1, Addr: "1"}, {ID: 2, Addr: "2"}},
+	})
+	if err != nil {
+		t.Fatalf("Connect() error = %v", err)
+	}
+	if err = c.(*cluster[uint64, *dummyConn]).sync(context.Background(), ConfigSourceFunc(func(context.Context) ([]ShardConfig, error) {
+		return []ShardConfig{{ID: 3, Addr: "3"}}, nil
+	})); err != nil {
+		t.Fatalf("sync() error = %v", err)
+	}
+	if got := c.IDs(); !reflect.DeepEqual(got, []int64{3}) {
+		t.Errorf("IDs() = %v, want [3]", got)
+	}
+	if err = c.Watch(context.Background(), nil, 0); err == nil {
+		t.Error("Watch() with a zero interval error = nil, want error")
+	}
+}
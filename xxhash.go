@@ -0,0 +1,110 @@
+package sharding
+
+import (
+	"math/bits"
+	"strconv"
+)
+
+// NewXXHash returns a Hash based on the 64-bit xxHash (XXH64, seed 0).
+// Keys are encoded the same way as in NewDefaultHash, but without heap
+// allocations, which makes it considerably faster on hot paths.
+func NewXXHash[KeyType ID]() Hash[KeyType] {
+	return xxHash[KeyType]{}
+}
+
+type xxHash[KeyType ID] struct{}
+
+// Sum of id.
+func (xxHash[KeyType]) Sum(id KeyType) uint64 {
+	var buf [20]byte
+	switch i := any(id).(type) {
+	case int64:
+		return xxh64(strconv.AppendInt(buf[:0], i, 10))
+	case uint64:
+		return xxh64(strconv.AppendUint(buf[:0], i, 10))
+	case string:
+		return xxh64(i)
+	case []byte:
+		return xxh64(i)
+	}
+	return 0
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// xxh64 is a minimal implementation of XXH64 with seed 0. It accepts both
+// strings and byte slices so string keys don't have to be copied.
+func xxh64[T string | []byte](b T) uint64 {
+	var (
+		n    = len(b)
+		h    uint64
+		seed uint64
+	)
+	if n >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for len(b) >= 32 {
+			v1 = xxRound(v1, xxU64(b[0:8]))
+			v2 = xxRound(v2, xxU64(b[8:16]))
+			v3 = xxRound(v3, xxU64(b[16:24]))
+			v4 = xxRound(v4, xxU64(b[24:32]))
+			b = b[32:]
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) +
+			bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+	h += uint64(n)
+	for ; len(b) >= 8; b = b[8:] {
+		h ^= xxRound(0, xxU64(b[:8]))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(b) >= 4 {
+		h ^= uint64(xxU32(b[:4])) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		b = b[4:]
+	}
+	for i := 0; i < len(b); i++ {
+		h ^= uint64(b[i]) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+func xxU64[T string | []byte](b T) uint64 {
+	return uint64(b[0]) | uint64(b[1])<<8 | uint64(b[2])<<16 | uint64(b[3])<<24 |
+		uint64(b[4])<<32 | uint64(b[5])<<40 | uint64(b[6])<<48 | uint64(b[7])<<56
+}
+
+func xxU32[T string | []byte](b T) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}